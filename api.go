@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 
 	"github.com/google/uuid"
@@ -17,15 +16,10 @@ type API struct {
 	client http.Client
 }
 
-func NewAPI(tokenPath string) (API, error) {
-	t, err := os.ReadFile(tokenPath)
-	if err != nil {
-		return API{}, err
-	}
-
+func NewAPI(token string) API {
 	return API{
-		token: strings.TrimSpace(fmt.Sprintf("%s", t)),
-	}, nil
+		token: token,
+	}
 }
 
 type QuickAddResponse struct {
@@ -36,7 +30,7 @@ func (api API) getPending(ctx context.Context, token string) (SyncResponse, erro
 	var syncResponse SyncResponse
 	values := url.Values{
 		"sync_token":     {token},
-		"resource_types": {"[\"items\", \"projects\"]"},
+		"resource_types": {"[\"items\", \"projects\", \"sections\", \"labels\"]"},
 	}
 	s := values.Encode()
 	body := strings.NewReader(s)
@@ -55,6 +49,36 @@ func (api API) getPending(ctx context.Context, token string) (SyncResponse, erro
 	return syncResponse, err
 }
 
+type CompletedResponse struct {
+	Items []CompletedItem `json:"items"`
+}
+
+// One page of completed tasks, the latest first
+func (api API) getCompleted(ctx context.Context, limit, offset int) ([]CompletedItem, error) {
+	values := url.Values{
+		"limit":  {fmt.Sprint(limit)},
+		"offset": {fmt.Sprint(offset)},
+	}
+	body := strings.NewReader(values.Encode())
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.todoist.com/sync/v9/completed/get_all", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+api.token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("completed/get_all returned %d status code", res.StatusCode)
+	}
+	var completedResponse CompletedResponse
+	err = json.NewDecoder(res.Body).Decode(&completedResponse)
+	return completedResponse.Items, err
+}
+
 // returns the id of the new todo
 func (api API) quickAdd(ctx context.Context, content string) (string, error) {
 	values := url.Values{
@@ -108,31 +132,93 @@ func (api API) newTask(ctx context.Context, todo Todo) error {
 }
 
 func (api API) makeChild(ctx context.Context, parentId, childId string) error {
-	v := fmt.Sprintf(`[
-    {
-        "type": "item_move",
-        "uuid": "%s",
-        "args": {
-            "id": "%s", 
-            "parent_id": "%s"
-        }
-    }]`, uuid.New().String(), childId, parentId)
+	cmd := newSyncCommand("item_move", map[string]interface{}{
+		"id":        childId,
+		"parent_id": parentId,
+	})
+	res, err := api.sync(ctx, []SyncCommand{cmd})
+	if err != nil {
+		return err
+	}
+	return res.err(cmd)
+}
+
+// https://developer.todoist.com/sync/v9/#write-resources
+type SyncCommand struct {
+	Type   string                 `json:"type"`
+	Uuid   string                 `json:"uuid"`
+	TempId string                 `json:"temp_id,omitempty"`
+	Args   map[string]interface{} `json:"args"`
+}
+
+func newSyncCommand(t string, args map[string]interface{}) SyncCommand {
+	return SyncCommand{
+		Type: t,
+		Uuid: uuid.New().String(),
+		Args: args,
+	}
+}
+
+type SyncCommandResponse struct {
+	// Either "ok" or an error object
+	SyncStatus    map[string]json.RawMessage `json:"sync_status"`
+	TempIdMapping map[string]string          `json:"temp_id_mapping"`
+}
+
+// Returns the error reported by the server for the given command, if any
+func (r SyncCommandResponse) err(cmd SyncCommand) error {
+	status, ok := r.SyncStatus[cmd.Uuid]
+	if !ok {
+		return fmt.Errorf("%s: no sync status returned", cmd.Type)
+	}
+	var s string
+	if json.Unmarshal(status, &s) == nil && s == "ok" {
+		return nil
+	}
+	var e struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(status, &e)
+	return fmt.Errorf("%s: %s", cmd.Type, e.Error)
+}
+
+// Sends write commands to the sync api
+func (api API) sync(ctx context.Context, commands []SyncCommand) (SyncCommandResponse, error) {
+	var syncResponse SyncCommandResponse
+	b, err := json.Marshal(commands)
+	if err != nil {
+		return syncResponse, err
+	}
 	values := url.Values{
-		"commands": {v},
+		"commands": {string(b)},
 	}
-	s := values.Encode()
-	body := strings.NewReader(s)
+	body := strings.NewReader(values.Encode())
 	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.todoist.com/sync/v9/sync", body)
 	if err != nil {
-		return err
+		return syncResponse, err
 	}
 	req.Header.Add("Authorization", "Bearer "+api.token)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	_, err = http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return syncResponse, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return syncResponse, fmt.Errorf("sync returned %d status code", res.StatusCode)
+	}
+	err = json.NewDecoder(res.Body).Decode(&syncResponse)
+	return syncResponse, err
+}
+
+func (api API) moveTask(ctx context.Context, id string, args map[string]interface{}) error {
+	args["id"] = id
+	cmd := newSyncCommand("item_move", args)
+	res, err := api.sync(ctx, []SyncCommand{cmd})
 	if err != nil {
 		return err
 	}
-	return nil
+	return res.err(cmd)
 }
 
 // NOTE: if makechild fails we are in a wierd state...
@@ -149,29 +235,51 @@ func (api API) newChild(ctx context.Context, parentId string, content string) er
 //
 // Currently only sending content and description
 type EditRequest struct {
-	Content     string   `json:"content,omitempty"`
-	Description string   `json:"description,omitempty"`
-	Due         string   `json:"due_date,omitempty"`
-	Labels      []string `json:"labels,omitempty"`
-	Priority    int      `json:"priority,omitempty"`
-	DueString   string   `json:"due_string,omitempty"`
+	Content     string    `json:"content,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Due         string    `json:"due_date,omitempty"`
+	DueDatetime string    `json:"due_datetime,omitempty"`
+	Labels      *[]string `json:"labels,omitempty"` // Always set, an empty list removes the labels
+	Priority    int       `json:"priority,omitempty"`
+	DueString   string    `json:"due_string,omitempty"`
 }
 
-// TODO: somehow support editing of children
-func (api API) editTask(ctx context.Context, todo Todo) error {
-	url := fmt.Sprintf("https://api.todoist.com/rest/v2/tasks/%s", todo.Id)
+// The due is only sent when it changed from the old one, since a date would make a recurring task a one-off task
+func newEditRequest(todo Todo, old Due) EditRequest {
+	labels := todo.Labels
+	if labels == nil {
+		labels = []string{}
+	}
 	t := EditRequest{
 		Content:     todo.Content,
 		Description: todo.Description,
-		Due:         todo.Due.Date,
-		Labels:      todo.Labels,
+		Labels:      &labels,
 		Priority:    todo.Priority,
-		DueString:   todo.Due.ChangeString,
 	}
-	if t.DueString != "" { // If change string is set, then we dont want to set due. To avoid conflicts
-		t.Due = ""
+	if todo.Due.ChangeString != "" { // If change string is set, then we dont want to set due. To avoid conflicts
+		t.DueString = todo.Due.ChangeString
+		return t
+	}
+	if todo.Due.Date == old.Date {
+		return t
+	}
+	due, hasTime, err := todo.Due.Time()
+	switch {
+	case err != nil || !hasTime:
+		t.Due = todo.Due.Date
+	case todo.Due.Timezone != "":
+		t.DueDatetime = due.UTC().Format(dueFixedLayout)
+	default:
+		// A due_datetime would pin a floating time to a timezone
+		t.DueString = due.Format("2006-01-02 15:04")
 	}
-	body, err := json.Marshal(t)
+	return t
+}
+
+// TODO: somehow support editing of children
+func (api API) editTask(ctx context.Context, todo Todo, old Due) error {
+	url := fmt.Sprintf("https://api.todoist.com/rest/v2/tasks/%s", todo.Id)
+	body, err := json.Marshal(newEditRequest(todo, old))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Board tab: the tasks of one project as a kanban board.
+// Columns are the sections of the project, or a chosen set of labels.
+// Moving a card to another column moves the task to that section, or swaps the label.
+
+type board struct {
+	projectId string
+	column    int
+	byLabels  bool
+}
+
+type boardColumn struct {
+	title     string
+	sectionId string
+	label     string
+	todos     []Todo
+}
+
+const boardColumnMinWidth = 24
+
+type Sections struct {
+	data []Section
+}
+
+func (m model) getLocalSections() tea.Msg {
+	sections, err := m.storage.localSections()
+	if err != nil {
+		return SyncError{
+			err: err,
+		}
+	}
+	return Sections{
+		data: sections,
+	}
+}
+
+type Labels struct {
+	data []Label
+}
+
+func (m model) getLocalLabels() tea.Msg {
+	labels, err := m.storage.localLabels()
+	if err != nil {
+		return SyncError{
+			err: err,
+		}
+	}
+	return Labels{
+		data: labels,
+	}
+}
+
+func (m model) moveToSection(todo Todo, sectionId string) func() tea.Msg {
+	return func() tea.Msg {
+		return syncResult(m.storage.moveToSection(todo, sectionId))
+	}
+}
+
+// Columns by section. Tasks without a section comes first.
+func sectionColumns(list []Todo, sections []Section, projectId string) []boardColumn {
+	columns := []boardColumn{{title: "(No section)", todos: make([]Todo, 0)}}
+	for _, s := range sections {
+		if s.ProjectId == projectId {
+			columns = append(columns, boardColumn{title: s.Name, sectionId: s.Id, todos: make([]Todo, 0)})
+		}
+	}
+	for _, t := range list {
+		if t.ProjectId != projectId {
+			continue
+		}
+		for i := range columns {
+			if columns[i].sectionId == t.SectionId {
+				columns[i].todos = append(columns[i].todos, t)
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// Columns by label. A task is placed in the column of the first label it has,
+// tasks with none of the labels comes first.
+func labelColumns(list []Todo, labels []string, projectId string) []boardColumn {
+	columns := []boardColumn{{title: "(No label)", todos: make([]Todo, 0)}}
+	for _, l := range labels {
+		columns = append(columns, boardColumn{title: "@" + l, label: l, todos: make([]Todo, 0)})
+	}
+	for _, t := range list {
+		if t.ProjectId != projectId {
+			continue
+		}
+		column := 0
+		for i, l := range labels {
+			if Contains(t.Labels, l) {
+				column = i + 1
+				break
+			}
+		}
+		columns[column].todos = append(columns[column].todos, t)
+	}
+	return columns
+}
+
+func (m model) boardColumns() []boardColumn {
+	if m.board.byLabels && len(m.boardLabels) > 0 {
+		return labelColumns(m.filteredTodos, m.boardLabels, m.board.projectId)
+	}
+	return sectionColumns(m.filteredTodos, m.sections, m.board.projectId)
+}
+
+// Tasks in the focused column
+func (m model) boardTodos() []Todo {
+	columns := m.boardColumns()
+	if m.board.column >= len(columns) {
+		return []Todo{}
+	}
+	return columns[m.board.column].todos
+}
+
+// Projects that has tasks, in the order they appear
+func boardProjects(list []Todo) []string {
+	projects := make([]string, 0)
+	for _, t := range list {
+		if t.ProjectId != "" && !Contains(projects, t.ProjectId) {
+			projects = append(projects, t.ProjectId)
+		}
+	}
+	return projects
+}
+
+func (m *model) setBoardProject(projectId string) {
+	if projectId == "" || projectId == m.board.projectId {
+		return
+	}
+	m.board.projectId = projectId
+	m.board.column = 0
+	m.cursor.index = 0
+}
+
+// Labels of the task, with the label of the column swapped
+func moveToLabel(todo Todo, labels []string, label string) Todo {
+	newLabels := make([]string, 0, len(todo.Labels)+1)
+	for _, l := range todo.Labels {
+		if !Contains(labels, l) {
+			newLabels = append(newLabels, l)
+		}
+	}
+	if label != "" {
+		newLabels = append(newLabels, label)
+	}
+	todo.Labels = newLabels
+	return todo
+}
+
+func (m model) moveCard(delta int) (model, tea.Cmd) {
+	columns := m.boardColumns()
+	target := m.board.column + delta
+	if target < 0 || target >= len(columns) {
+		return m, nil
+	}
+	todo, err := m.getCurrentTodo()
+	if err != nil {
+		return m, nil
+	}
+	m.board.column = target
+	m.syncing = true
+	if m.board.byLabels && len(m.boardLabels) > 0 {
+		return m, m.editTask(EditTaskData{
+			todo: moveToLabel(todo, m.boardLabels, columns[target].label),
+		})
+	}
+	return m, m.moveToSection(todo, columns[target].sectionId)
+}
+
+// Handles the keys that are specific to the board tab.
+// Returns false if the key should be handled as in the normal list view.
+func (m model) updateBoard(msg tea.KeyMsg) (model, tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, m.keys.Left):
+		if m.board.column > 0 {
+			m.board.column--
+			m.refreshCursor()
+		}
+	case key.Matches(msg, m.keys.Right):
+		if m.board.column < len(m.boardColumns())-1 {
+			m.board.column++
+			m.refreshCursor()
+		}
+	case key.Matches(msg, m.keys.MoveCardLeft):
+		m, cmd := m.moveCard(-1)
+		return m, cmd, true
+	case key.Matches(msg, m.keys.MoveCardRight):
+		m, cmd := m.moveCard(1)
+		return m, cmd, true
+	case key.Matches(msg, m.keys.NextProject, m.keys.PrevProject):
+		projects := boardProjects(m.todos)
+		if len(projects) == 0 {
+			return m, nil, true
+		}
+		i := 0
+		for j, p := range projects {
+			if p == m.board.projectId {
+				i = j
+			}
+		}
+		if key.Matches(msg, m.keys.NextProject) {
+			i = (i + 1) % len(projects)
+		} else {
+			i = (i - 1 + len(projects)) % len(projects)
+		}
+		m.setBoardProject(projects[i])
+	case key.Matches(msg, m.keys.BoardMode):
+		if len(m.boardLabels) > 0 {
+			m.board.byLabels = !m.board.byLabels
+			m.board.column = 0
+			m.cursor.index = 0
+		}
+	default:
+		return m, nil, false
+	}
+	return m, nil, true
+}
+
+type boardLayout struct {
+	first   int // first visible column
+	visible int // number of visible columns
+	width   int // content width of a column
+	height  int // number of cards that fits in a column
+	offset  int // first visible card in the focused column
+}
+
+// Only the columns that fits are shown, around the focused one.
+// The focused column is scrolled so the cursor is always visible.
+func (m model) boardLayout(columns int) boardLayout {
+	l := boardLayout{}
+	l.visible = m.totalWidth / boardColumnMinWidth
+	if l.visible < 1 {
+		l.visible = 1
+	}
+	if l.visible > columns {
+		l.visible = columns
+	}
+	l.first = m.board.column - l.visible/2
+	if l.first < 0 {
+		l.first = 0
+	}
+	if l.first+l.visible > columns {
+		l.first = columns - l.visible
+	}
+	if l.visible > 0 {
+		l.width = m.totalWidth/l.visible - 4 // border and padding
+	}
+	l.height = m.listHeight - 4 // header, border and column title
+	if m.cursor.index >= l.height {
+		l.offset = m.cursor.index - l.height + 1
+	}
+	return l
+}
+
+func (m model) renderBoard() string {
+	columns := m.boardColumns()
+	if len(columns) == 0 {
+		return ""
+	}
+	project := ""
+	for _, t := range m.todos {
+		if t.ProjectId == m.board.projectId {
+			project = t.ProjectName
+			break
+		}
+	}
+	header := "  " + projectStyle.Render("#"+project)
+	if m.board.byLabels && len(m.boardLabels) > 0 {
+		header += dimTextStyle.Render("  by label")
+	} else {
+		header += dimTextStyle.Render("  by section")
+	}
+
+	l := m.boardLayout(len(columns))
+	width, height := l.width, l.height
+	rendered := make([]string, 0, l.visible)
+	for i := l.first; i < l.first+l.visible; i++ {
+		c := columns[i]
+		focused := i == m.board.column
+		title := fmt.Sprintf("%s (%d)", c.title, len(c.todos))
+		if focused {
+			title = chosenTextStyle.Render(withSize(title, width))
+		} else {
+			title = dimTextStyle.Render(withSize(title, width))
+		}
+		offset := 0
+		if focused {
+			offset = l.offset
+		}
+		content := title
+		for j := offset; j < len(c.todos) && j-offset < height; j++ {
+			t := c.todos[j]
+			card := withSize(t.Content, width-3)
+			if p := displayPrioriy(t.Priority); p != "  " {
+				card = p + " " + withSize(t.Content, width-6)
+			}
+			if focused && j == m.cursor.index {
+				content += "\n" + "→ " + card
+			} else {
+				content += "\n" + "  " + card
+			}
+		}
+		style := boardColumnStyle
+		if focused {
+			style = boardFocusedColumnStyle
+		}
+		rendered = append(rendered, style.Copy().Width(width+2).Height(height+1).Render(content))
+	}
+	return header + "\n" + lipgloss.JoinHorizontal(lipgloss.Top, rendered...)
+}
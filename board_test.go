@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSectionColumns(t *testing.T) {
+	sections := []Section{
+		{Id: "1", ProjectId: "p", Name: "Todo"},
+		{Id: "2", ProjectId: "p", Name: "Done"},
+		{Id: "3", ProjectId: "other", Name: "Other"},
+	}
+	todos := []Todo{
+		{Id: "a", ProjectId: "p", SectionId: "1"},
+		{Id: "b", ProjectId: "p", SectionId: "2"},
+		{Id: "c", ProjectId: "p"},
+		{Id: "d", ProjectId: "other", SectionId: "3"},
+	}
+	columns := sectionColumns(todos, sections, "p")
+	require.Equal(t, 3, len(columns))
+	require.Equal(t, "c", columns[0].todos[0].Id)
+	require.Equal(t, "Todo", columns[1].title)
+	require.Equal(t, "a", columns[1].todos[0].Id)
+	require.Equal(t, "2", columns[2].sectionId)
+	require.Equal(t, "b", columns[2].todos[0].Id)
+}
+
+func TestLabelColumns(t *testing.T) {
+	todos := []Todo{
+		{Id: "a", ProjectId: "p", Labels: []string{"work", "doing"}},
+		{Id: "b", ProjectId: "p", Labels: []string{"review"}},
+		{Id: "c", ProjectId: "p", Labels: []string{"work"}},
+	}
+	columns := labelColumns(todos, []string{"doing", "review"}, "p")
+	require.Equal(t, 3, len(columns))
+	require.Equal(t, "c", columns[0].todos[0].Id)
+	require.Equal(t, "a", columns[1].todos[0].Id)
+	require.Equal(t, "b", columns[2].todos[0].Id)
+}
+
+func TestMoveToLabel(t *testing.T) {
+	todo := Todo{Labels: []string{"work", "doing"}}
+	moved := moveToLabel(todo, []string{"doing", "review"}, "review")
+	require.Equal(t, []string{"work", "review"}, moved.Labels)
+
+	moved = moveToLabel(todo, []string{"doing", "review"}, "")
+	require.Equal(t, []string{"work"}, moved.Labels)
+
+	// To the "(No label)" column, the empty list has to be sent to remove the label
+	moved = moveToLabel(Todo{Labels: []string{"doing"}}, []string{"doing", "review"}, "")
+	body, err := json.Marshal(newEditRequest(moved, moved.Due))
+	require.NoError(t, err)
+	require.Contains(t, string(body), `"labels":[]`)
+	body, err = json.Marshal(newEditRequest(Todo{Content: "No labels"}, Due{}))
+	require.NoError(t, err)
+	require.Contains(t, string(body), `"labels":[]`)
+
+	// Only the labels change, the due is left out so the task keeps recurring
+	recurring := Todo{Labels: []string{"doing"}, Due: Due{Date: "2024-01-05", String: "every friday", IsRecurring: true}}
+	moved = moveToLabel(recurring, []string{"doing", "review"}, "review")
+	body, err = json.Marshal(newEditRequest(moved, recurring.Due))
+	require.NoError(t, err)
+	require.NotContains(t, string(body), `"due_`)
+	body, err = json.Marshal(newEditRequest(Todo{Due: Due{Date: "2024-01-06"}}, recurring.Due))
+	require.NoError(t, err)
+	require.Contains(t, string(body), `"due_date":"2024-01-06"`)
+}
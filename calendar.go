@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Calendar tab: a month grid with the number of tasks per day.
+// The tasks of the selected day are listed below the grid.
+//
+// A task can be picked up from the list and dropped onto another day, to reschedule it.
+
+type calendar struct {
+	day       time.Time // selected day
+	focusList bool      // keys go to the task list below the grid
+	grabbing  bool
+	grabbed   Todo
+}
+
+const calendarCellWidth = 8
+
+// Tasks due on the given day
+func filterDay(list []Todo, day time.Time) []Todo {
+	var newList = make([]Todo, 0)
+	for _, t := range list {
+		due, _, err := t.Due.Time()
+		if err != nil {
+			continue
+		}
+		if daysBetween(day, due) == 0 {
+			newList = append(newList, t)
+		}
+	}
+	return newList
+}
+
+type calendarDayInfo struct {
+	count   int
+	overdue bool
+}
+
+// Number of tasks per day, keyed by date
+func countByDay(list []Todo) map[string]calendarDayInfo {
+	days := make(map[string]calendarDayInfo)
+	for _, t := range list {
+		due, _, err := t.Due.Time()
+		if err != nil {
+			continue
+		}
+		k := due.Format(dueDateLayout)
+		info := days[k]
+		info.count++
+		info.overdue = info.overdue || t.Overdue()
+		days[k] = info
+	}
+	return days
+}
+
+// The monday of the week the month starts in
+func calendarStart(day time.Time) time.Time {
+	first := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	offset := (int(first.Weekday()) + 6) % 7
+	return first.AddDate(0, 0, -offset)
+}
+
+// Number of weeks shown in the month grid
+func calendarWeeks(day time.Time) int {
+	d := calendarStart(day)
+	weeks := 0
+	for weeks == 0 || (weeks < 6 && d.Month() == day.Month()) {
+		weeks++
+		d = d.AddDate(0, 0, 7)
+	}
+	return weeks
+}
+
+// Lines above the task list: month, weekdays, the weeks, a blank line and the day header
+func calendarHeight(day time.Time) int {
+	return calendarWeeks(day) + 4
+}
+
+func (m *model) moveCalendarDay(days, months int) {
+	d := m.calendar.day
+	if months != 0 {
+		d = addMonths(d, months)
+	}
+	m.calendar.day = d.AddDate(0, 0, days)
+	m.calendarTodos = filterDay(m.filteredTodos, m.calendar.day)
+	m.cursor.index = 0
+}
+
+// Handles the keys that are specific to the calendar tab.
+// Returns false if the key should be handled as in the normal list view.
+func (m model) updateCalendar(msg tea.KeyMsg) (model, tea.Cmd, bool) {
+	if m.calendar.focusList {
+		switch {
+		case key.Matches(msg, m.keys.ExitInput):
+			m.calendar.focusList = false
+			return m, nil, true
+		case key.Matches(msg, m.keys.Reschedule):
+			todo, err := m.getCurrentTodo()
+			if err != nil {
+				return m, nil, true
+			}
+			m.calendar.grabbing = true
+			m.calendar.grabbed = todo
+			m.calendar.focusList = false
+			return m, nil, true
+		}
+		return m, nil, false
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Left):
+		m.moveCalendarDay(-1, 0)
+	case key.Matches(msg, m.keys.Right):
+		m.moveCalendarDay(1, 0)
+	case key.Matches(msg, m.keys.Up):
+		m.moveCalendarDay(-7, 0)
+	case key.Matches(msg, m.keys.Down):
+		m.moveCalendarDay(7, 0)
+	case key.Matches(msg, m.keys.PrevMonth):
+		m.moveCalendarDay(0, -1)
+	case key.Matches(msg, m.keys.NextMonth):
+		m.moveCalendarDay(0, 1)
+	case key.Matches(msg, m.keys.GoToToday):
+		m.calendar.day = startOfDay(dateFormatter.now())
+		m.moveCalendarDay(0, 0)
+	case key.Matches(msg, m.keys.ExitInput):
+		m.calendar.grabbing = false
+	case key.Matches(msg, m.keys.Select):
+		if m.calendar.grabbing {
+			// Drop the task onto the selected day
+			m.calendar.grabbing = false
+			m.syncing = true
+			return m, m.editTask(EditTaskData{
+				todo: rescheduleTodo(m.calendar.grabbed, m.calendar.day),
+			}), true
+		}
+		if len(m.calendarTodos) > 0 {
+			m.calendar.focusList = true
+		}
+	default:
+		return m, nil, false
+	}
+	return m, nil, true
+}
+
+func (m model) renderCalendar(todos []Todo) string {
+	day := m.calendar.day
+	today := startOfDay(dateFormatter.now())
+	counts := countByDay(m.filteredTodos)
+	cell := lipgloss.NewStyle().Width(calendarCellWidth)
+
+	s := "  " + chosenTextStyle.Render(day.Format("January 2006"))
+	if m.calendar.grabbing {
+		s += dimTextStyle.Render("  moving: ") + withSize(m.calendar.grabbed.Content, 40)
+	}
+	s += "\n  "
+	for _, w := range []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"} {
+		s += cell.Copy().Inherit(dimTextStyle).Render(w)
+	}
+	s += "\n"
+
+	d := calendarStart(day)
+	for week := 0; week < calendarWeeks(day); week++ {
+		row := "  "
+		for i := 0; i < 7; i++ {
+			text := fmt.Sprintf("%2d", d.Day())
+			info := counts[d.Format(dueDateLayout)]
+			if info.count > 0 {
+				text += fmt.Sprintf(" (%d)", info.count)
+			}
+			if info.overdue {
+				text += "!"
+			}
+			style := cell.Copy()
+			switch {
+			case d.Month() != day.Month():
+				style = style.Inherit(dimTextStyle)
+			case info.overdue:
+				style = style.Inherit(dueDateOverdueStyle)
+			case d.Equal(today):
+				style = style.Inherit(calendarTodayStyle)
+			}
+			if d.Equal(day) {
+				style = style.Inherit(calendarSelectedStyle)
+			}
+			row += style.Render(text)
+			d = d.AddDate(0, 0, 1)
+		}
+		s += row + "\n"
+	}
+	s += "\n"
+
+	list := m
+	if !m.calendar.focusList {
+		list.cursor.index = -1
+	}
+	header := dimTextStyle.Render("  " + dateFormatter.format(day, false) + " · " + day.Format("Mon 02 Jan"))
+	return s + header + "\n" + list.renderViewList(todos)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarStart(t *testing.T) {
+	// February 2024 starts on a thursday
+	start := calendarStart(time.Date(2024, 2, 14, 0, 0, 0, 0, time.Local))
+	require.Equal(t, time.Date(2024, 1, 29, 0, 0, 0, 0, time.Local), start)
+	require.Equal(t, time.Monday, start.Weekday())
+
+	// April 2024 starts on a monday
+	start = calendarStart(time.Date(2024, 4, 30, 0, 0, 0, 0, time.Local))
+	require.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local), start)
+}
+
+func TestCountByDay(t *testing.T) {
+	todos := []Todo{
+		{Id: "1", Due: Due{Date: "2024-02-01"}},
+		{Id: "2", Due: Due{Date: "2024-02-01T10:00:00"}},
+		{Id: "3", Due: Due{Date: "2024-02-02"}},
+		{Id: "4"},
+	}
+	counts := countByDay(todos)
+	require.Equal(t, 2, len(counts))
+	require.Equal(t, 2, counts["2024-02-01"].count)
+	require.True(t, counts["2024-02-01"].overdue)
+
+	day := filterDay(todos, time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local))
+	require.Equal(t, 2, len(day))
+}
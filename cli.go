@@ -0,0 +1,409 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Subcommands for scripts, run instead of the TUI when arguments are given:
+//
+//	todui add "Buy milk tomorrow #Home @errand"
+//	todui list --filter "#Work"
+//	todui done <id|query>
+//	todui edit <id>
+//	todui show <id>
+//	todui sync [--full]
+//	todui export --format todotxt
+//	todui import --format todotxt todo.txt
+//	todui serve
+//
+// Output goes to stdout and errors to stderr. The exit codes are listed below.
+
+const (
+	exitOK        = 0
+	exitError     = 1
+	exitUsage     = 2
+	exitNotFound  = 3
+	exitAmbiguous = 4
+)
+
+var (
+	errUsage     = errors.New("usage")
+	errNotFound  = errors.New("no task found")
+	errAmbiguous = errors.New("more than one task matches")
+)
+
+type cli struct {
+	storage Storage
+	config  Config
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+type cliCommand struct {
+	name  string
+	usage string
+	desc  string
+	run   func(c cli, args []string) error
+}
+
+func cliCommands() []cliCommand {
+	return []cliCommand{
+		{name: "add", usage: "add <quick add text>", desc: "add a task with todoist quick add, prints the id", run: cli.add},
+		{name: "list", usage: "list [--filter <filter>] [--local] [--output <format>] [--fields <fields>]", desc: "list tasks", run: cli.list},
+		{name: "done", usage: "done <id|query>", desc: "mark a task as done", run: cli.done},
+		{name: "edit", usage: "edit <id|query>", desc: "edit a task in the editor", run: cli.edit},
+		{name: "show", usage: "show [--output <format>] [--fields <fields>] <id|query>", desc: "show the details of a task", run: cli.show},
+		{name: "sync", usage: "sync [--full]", desc: "sync with todoist", run: cli.sync},
+		{name: "export", usage: "export --format <format> [--filter <filter>] [--completed] [--events] [file]", desc: "export tasks to another format", run: cli.exportFile},
+		{name: "import", usage: "import --format <format> [--dry-run] [--project <name>] <file>", desc: "create tasks from another format", run: cli.importFile},
+		{name: "serve", usage: "serve [--addr <host:port>] [--token-file <path>]", desc: "serve a json api of the tasks on localhost", run: cli.serve},
+		{name: "serve-ics", usage: "serve-ics [--addr <host:port>] [--events]", desc: "serve the tasks as an iCalendar feed", run: cli.serveICS},
+	}
+}
+
+func isCLICommand(name string) bool {
+	for _, c := range cliCommands() {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func cliUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: todui [flags] [command]\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 4, 3, ' ', 0)
+	for _, c := range cliCommands() {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.usage, c.desc)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nWithout a command the TUI is started.\n")
+	fmt.Fprintf(w, "\nExit codes: 0 ok, 1 error, 2 usage, 3 no task found, 4 more than one task matches\n")
+}
+
+// Runs the command and returns the exit code
+func runCLI(c cli, args []string) int {
+	for _, command := range cliCommands() {
+		if command.name != args[0] {
+			continue
+		}
+		err := command.run(c, args[1:])
+		switch {
+		case err == nil:
+			return exitOK
+		case errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(c.stderr, "%s\nusage: todui %s\n", err, command.usage)
+			return exitUsage
+		}
+		fmt.Fprintf(c.stderr, "todui %s: %s\n", command.name, err)
+		var ambiguous ambiguousError
+		if errors.As(err, &ambiguous) {
+			for _, t := range ambiguous.matches {
+				fmt.Fprintf(c.stderr, "%s\t%s\n", t.Id, t.Content)
+			}
+		}
+		switch {
+		case errors.Is(err, errNotFound):
+			return exitNotFound
+		case errors.Is(err, errAmbiguous):
+			return exitAmbiguous
+		}
+		return exitError
+	}
+	fmt.Fprintf(c.stderr, "unknown command %q\n\n", args[0])
+	cliUsage(c.stderr)
+	return exitUsage
+}
+
+func usageError(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{errUsage}, a...)...)
+}
+
+func (c cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// Parses the flags, errors from the flag package are usage errors
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	return err
+}
+
+func (c cli) add(args []string) error {
+	fs := c.flagSet("add")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	content := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if content == "" {
+		return usageError("missing task")
+	}
+	id, _, err := c.storage.quickAdd(content)
+	if err != nil && id == "" {
+		return err
+	}
+	// The task is added even if the sync after it failed, so it must not be added again
+	fmt.Fprintln(c.stdout, id)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "todui add: warning: %s\n", err)
+	}
+	return nil
+}
+
+func (c cli) list(args []string) error {
+	fs := c.flagSet("list")
+	filter := fs.String("filter", "", "Filter like in the TUI, e.g. \"#Project @label word\".")
+	local := fs.Bool("local", false, "Use the local db without syncing first.")
+	format, fields := outputFlags(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("unexpected argument %q", fs.Arg(0))
+	}
+	out, err := parseOutput(*format, *fields)
+	if err != nil {
+		return err
+	}
+	todos, err := c.todos(*local)
+	if err != nil {
+		return err
+	}
+	todos = filterContents(todos, *filter)
+	sortTodos(todos, c.config.Sort)
+	return out.write(c.stdout, todos)
+}
+
+func outputFlags(fs *flag.FlagSet) (*string, *string) {
+	format := fs.String("output", outputText, "Output format: "+strings.Join(outputFormats, ", "))
+	fields := fs.String("fields", "", "Comma separated fields to output: "+strings.Join(taskFields, ", "))
+	return format, fields
+}
+
+func (c cli) done(args []string) error {
+	todo, err := c.findArg(c.flagSet("done"), args)
+	if err != nil {
+		return err
+	}
+	_, err = c.storage.markAsDone(todo)
+	return c.warnRejected(err)
+}
+
+func (c cli) edit(args []string) error {
+	todo, err := c.findArg(c.flagSet("edit"), args)
+	if err != nil {
+		return err
+	}
+	path, err := createEditFile(todo)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	cmd := editorCommand(c.config.Editor, path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("editor: %w", err)
+	}
+	todo, updateChildren, err := parseEditFile(path, todo)
+	if err != nil {
+		return err
+	}
+	_, err = c.storage.editTask(EditTaskData{
+		todo:           todo,
+		updateChildren: updateChildren,
+	})
+	return c.warnRejected(err)
+}
+
+func (c cli) show(args []string) error {
+	fs := c.flagSet("show")
+	format, fields := outputFlags(fs)
+	todo, err := c.findArg(fs, args)
+	if err != nil {
+		return err
+	}
+	out, err := parseOutput(*format, *fields)
+	if err != nil {
+		return err
+	}
+	if out.format != outputText || *fields != "" {
+		return out.writeOne(c.stdout, todo)
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 1, ' ', 0)
+	fmt.Fprintf(tw, "id:\t%s\n", todo.Id)
+	fmt.Fprintf(tw, "content:\t%s\n", todo.Content)
+	fmt.Fprintf(tw, "project:\t%s\n", todo.ProjectName)
+	if todo.Due.Date != "" {
+		due := todo.Due.Date
+		if todo.Due.IsRecurring {
+			due += " (" + todo.Due.String + ")"
+		}
+		fmt.Fprintf(tw, "due:\t%s\n", due)
+	}
+	fmt.Fprintf(tw, "priority:\t%s\n", renderPriority(todo.Priority))
+	if len(todo.Labels) > 0 {
+		fmt.Fprintf(tw, "labels:\t%s\n", strings.Join(todo.Labels, ", "))
+	}
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+	if todo.Description != "" {
+		fmt.Fprintf(c.stdout, "\n%s\n", strings.TrimSpace(todo.Description))
+	}
+	if len(todo.Children) > 0 {
+		fmt.Fprintln(c.stdout)
+		for _, child := range todo.Children {
+			check := " "
+			if child.Checked {
+				check = "x"
+			}
+			fmt.Fprintf(c.stdout, "- [%s] %s (%s)\n", check, child.Content, child.Id)
+		}
+	}
+	return nil
+}
+
+func (c cli) sync(args []string) error {
+	fs := c.flagSet("sync")
+	full := fs.Bool("full", false, "Fetch everything again and rebuild the local db, like the -sync flag.")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("unexpected argument %q", fs.Arg(0))
+	}
+	if *full {
+		report, err := c.storage.fullSync()
+		if err = c.warnRejected(err); err != nil {
+			return err
+		}
+		printSyncReport(c.stdout, report)
+		return nil
+	}
+	todos, err := c.storage.fetchTodos()
+	if err = c.warnRejected(err); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "%d tasks\n", len(todos))
+	return nil
+}
+
+func printSyncReport(w io.Writer, r SyncReport) {
+	fmt.Fprintf(w, "tasks:     %d added, %d updated, %d removed\n", r.Tasks.Added, r.Tasks.Updated, r.Tasks.Removed)
+	fmt.Fprintf(w, "projects:  %d added, %d updated, %d removed\n", r.Projects.Added, r.Projects.Updated, r.Projects.Removed)
+	if r.CompletedTruncated {
+		fmt.Fprintf(w, "completed: %d, the latest only\n", r.Completed)
+		return
+	}
+	fmt.Fprintf(w, "completed: %d\n", r.Completed)
+}
+
+func (c cli) todos(local bool) ([]Todo, error) {
+	if local {
+		return c.storage.localTodos()
+	}
+	todos, err := c.storage.fetchTodos()
+	return todos, c.warnRejected(err)
+}
+
+// Queued commands the server rejected are only a warning, the rest of the sync went through
+func (c cli) warnRejected(err error) error {
+	if !isRejected(err) {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "todui: warning: %s\n", err)
+	return nil
+}
+
+// Finds the task given as the only argument. The local db is used, since the id
+// usually comes from a list that was just synced.
+func (c cli) findArg(fs *flag.FlagSet, args []string) (Todo, error) {
+	if err := parseArgs(fs, args); err != nil {
+		return Todo{}, err
+	}
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" {
+		return Todo{}, usageError("missing task id or query")
+	}
+	todos, err := c.storage.localTodos()
+	if err != nil {
+		return Todo{}, err
+	}
+	return findTodo(todos, query)
+}
+
+type ambiguousError struct {
+	query   string
+	matches []Todo
+}
+
+func (e ambiguousError) Error() string {
+	return fmt.Sprintf("%d tasks matches %q", len(e.matches), e.query)
+}
+
+func (e ambiguousError) Unwrap() error {
+	return errAmbiguous
+}
+
+// The task with the id, or else the only task, or subtask, with the query in its content
+func findTodo(todos []Todo, query string) (Todo, error) {
+	all := make([]Todo, 0, len(todos))
+	var add func(list []Todo)
+	add = func(list []Todo) {
+		for _, t := range list {
+			all = append(all, t)
+			add(t.Children)
+		}
+	}
+	add(todos)
+	for _, t := range all {
+		if t.Id == query {
+			return t, nil
+		}
+	}
+	matches := make([]Todo, 0)
+	for _, t := range all {
+		if strings.Contains(strings.ToLower(t.Content), strings.ToLower(query)) {
+			matches = append(matches, t)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Todo{}, fmt.Errorf("%w: %q", errNotFound, query)
+	case 1:
+		return matches[0], nil
+	}
+	return Todo{}, ambiguousError{query: query, matches: matches}
+}
+
+func cliPriority(p int) string {
+	if p <= 1 {
+		return ""
+	}
+	return renderPriority(p)
+}
+
+func cliLabels(labels []string) string {
+	s := make([]string, len(labels))
+	for i, l := range labels {
+		s[i] = "@" + l
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindTodo(t *testing.T) {
+	todos := []Todo{
+		{Id: "1", Content: "Buy milk", Children: []Todo{{Id: "3", Content: "Oat milk"}}},
+		{Id: "2", Content: "Call mom"},
+	}
+	todo, err := findTodo(todos, "2")
+	require.NoError(t, err)
+	require.Equal(t, "Call mom", todo.Content)
+	todo, err = findTodo(todos, "OAT")
+	require.NoError(t, err)
+	require.Equal(t, "3", todo.Id)
+
+	_, err = findTodo(todos, "milk")
+	require.ErrorIs(t, err, errAmbiguous)
+	_, err = findTodo(todos, "bread")
+	require.ErrorIs(t, err, errNotFound)
+}
+
+func newTestCLI(t *testing.T) (cli, *bytes.Buffer, *bytes.Buffer) {
+	db, err := NewDB(fmt.Sprintf("testoutput/test-cli-%d.db", time.Now().UnixNano()))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	err = db.InsertFromSync(context.Background(), SyncResponse{
+		SyncToken: "testing",
+		Projects:  []Project{{Id: "11", Name: "Home"}, {Id: "12", Name: "Work"}},
+		Items: []Item{
+			{Id: "1", ProjectId: "11", Content: "Buy milk", Priority: 4, Labels: []string{"errand"}, Due: Due{Date: "2024-01-02"}},
+			{Id: "2", ProjectId: "12", Content: "Write report", Description: "for monday", Due: Due{Date: "2024-01-01"}},
+			{Id: "3", ProjectId: "12", ParentId: "2", Content: "Draft"},
+		},
+	})
+	require.NoError(t, err)
+	var stdout, stderr bytes.Buffer
+	c := cli{
+		storage: Storage{db: db},
+		config:  defaultConfig(),
+		stdout:  &stdout,
+		stderr:  &stderr,
+	}
+	return c, &stdout, &stderr
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// Answers the requests to the Todoist api instead of the network: syncs return nothing new.
+// Returns the bodies of the task edits sent.
+func fakeTodoist(t *testing.T) *[]string {
+	edits := make([]string, 0)
+	transport := http.DefaultClient.Transport
+	t.Cleanup(func() { http.DefaultClient.Transport = transport })
+	http.DefaultClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body := `{"sync_token": "testing"}`
+		if strings.HasPrefix(r.URL.Path, "/rest/v2/tasks/") {
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, string(b))
+			body = "{}"
+		}
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})
+	return &edits
+}
+
+func TestCLIList(t *testing.T) {
+	c, stdout, _ := newTestCLI(t)
+	require.Equal(t, exitOK, runCLI(c, []string{"list", "--local"}))
+	require.Equal(t, "2  2024-01-01      #Work  Write report  \n1  2024-01-02  p1  #Home  Buy milk      @errand\n", stdout.String())
+
+	stdout.Reset()
+	require.Equal(t, exitOK, runCLI(c, []string{"list", "--local", "--filter", "#Home"}))
+	require.Equal(t, "1  2024-01-02  p1  #Home  Buy milk  @errand\n", stdout.String())
+}
+
+func TestCLIShow(t *testing.T) {
+	c, stdout, stderr := newTestCLI(t)
+	require.Equal(t, exitOK, runCLI(c, []string{"show", "report"}))
+	require.Contains(t, stdout.String(), "id:       2\n")
+	require.Contains(t, stdout.String(), "project:  Work\n")
+	require.Contains(t, stdout.String(), "\nfor monday\n")
+	require.Contains(t, stdout.String(), "- [ ] Draft (3)\n")
+
+	stdout.Reset()
+	require.Equal(t, exitOK, runCLI(c, []string{"show", "--output", "ndjson", "--fields", "id,children", "report"}))
+	require.Equal(t, "{\"id\":\"2\",\"children\":[{\"id\":\"3\",\"children\":[]}]}\n", stdout.String())
+
+	require.Equal(t, exitNotFound, runCLI(c, []string{"show", "nope"}))
+	require.Equal(t, exitAmbiguous, runCLI(c, []string{"show", "r"}))
+	require.Contains(t, stderr.String(), "matches \"r\"\n2\tWrite report\n3\tDraft\n")
+}
+
+func TestCLIUsage(t *testing.T) {
+	c, _, stderr := newTestCLI(t)
+	require.Equal(t, exitUsage, runCLI(c, []string{"nope"}))
+	require.Contains(t, stderr.String(), "Commands:")
+	require.Equal(t, exitUsage, runCLI(c, []string{"show"}))
+	require.Equal(t, exitUsage, runCLI(c, []string{"add"}))
+	require.Equal(t, exitUsage, runCLI(c, []string{"list", "--nope"}))
+	require.Equal(t, exitUsage, runCLI(c, []string{"list", "extra"}))
+}
+
+func TestPrintSyncReport(t *testing.T) {
+	var b bytes.Buffer
+	printSyncReport(&b, SyncReport{Tasks: SyncCounts{Added: 2}, Completed: 2000, CompletedTruncated: true})
+	require.Equal(t, "tasks:     2 added, 0 updated, 0 removed\nprojects:  0 added, 0 updated, 0 removed\ncompleted: 2000, the latest only\n", b.String())
+}
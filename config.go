@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Settings are read from the config file, then from TODUI_* environment variables
+// (e.g. TODUI_DEFAULT_TAB), then from flags. Later ones take precedence.
+
+type Config struct {
+	Token         string              `toml:"token"`
+	TokenCommand  string              `toml:"token_command"`
+	TokenPath     string              `toml:"token_path"`
+	DBPath        string              `toml:"db_path"`
+	DefaultTab    string              `toml:"default_tab"`
+	Sort          string              `toml:"sort"`
+	ShowInfo      bool                `toml:"show_info"`
+	Editor        string              `toml:"editor"`
+	DateFormat    string              `toml:"date_format"`
+	SyncInterval  string              `toml:"sync_interval"`
+	UpcomingDays  int                 `toml:"upcoming_days"`
+	EmptyDays     bool                `toml:"empty_days"`
+	BoardLabels   []string            `toml:"board_labels"`
+	Leader        string              `toml:"leader"`
+	Keys          map[string][]string `toml:"keys"`
+	Theme         string              `toml:"theme"`
+	ProjectColors bool                `toml:"project_colors"`
+	Themes        map[string]Theme    `toml:"themes"`
+	Profile       string              `toml:"profile"`
+	Profiles      map[string]Profile  `toml:"profiles"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		DefaultTab:   "today",
+		Sort:         sortDue,
+		ShowInfo:     true,
+		DateFormat:   "02/01/2006",
+		SyncInterval: "0",
+		UpcomingDays: 7,
+		Leader:       "space",
+		Theme:        defaultTheme,
+		Profile:      defaultProfile,
+	}
+}
+
+// Loads the config file. A missing file gives the default config.
+func loadConfig(path string) (Config, error) {
+	config := defaultConfig()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	md, err := toml.DecodeFile(path, &config)
+	if err != nil {
+		return config, fmt.Errorf("config %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		names := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			names[i] = k.String()
+		}
+		return config, fmt.Errorf("config %s: unknown settings: %s", path, strings.Join(names, ", "))
+	}
+	return config, nil
+}
+
+// Sets a setting by its name in the config file, from a string as given in the environment or a flag.
+// Lists are comma separated.
+func (c *Config) set(name, value string) error {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("toml") != name {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			field.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			field.SetInt(int64(n))
+		case reflect.Slice:
+			list := make([]string, 0)
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					list = append(list, s)
+				}
+			}
+			field.Set(reflect.ValueOf(list))
+		default:
+			return fmt.Errorf("%s can only be set in the config file", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown setting %q", name)
+}
+
+// Overrides the settings that are set in the environment
+func (c *Config) applyEnv(getenv func(string) string) error {
+	t := reflect.TypeOf(*c)
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("toml")
+		kind := t.Field(i).Type.Kind()
+		if kind == reflect.Map {
+			continue
+		}
+		value := getenv("TODUI_" + strings.ToUpper(name))
+		if value == "" {
+			continue
+		}
+		if err := c.set(name, value); err != nil {
+			return fmt.Errorf("TODUI_%s: %w", strings.ToUpper(name), err)
+		}
+	}
+	return nil
+}
+
+// Names of the tabs in the config, indexed by Tab
+var tabNames = []string{"inbox", "today", "all", "completed", "upcoming", "calendar", "board"}
+
+func parseTab(name string) (Tab, error) {
+	for tab, n := range tabNames {
+		if n == name {
+			return tab, nil
+		}
+	}
+	return 0, fmt.Errorf("default_tab: unknown tab %q, expected one of %s", name, strings.Join(tabNames, ", "))
+}
+
+func parseSort(by string) (string, error) {
+	switch by {
+	case sortDue, sortPriority, sortProject:
+		return by, nil
+	}
+	return "", fmt.Errorf("sort: expected %s, %s or %s, got %q", sortDue, sortPriority, sortProject, by)
+}
+
+// Shorter intervals would hit the rate limit of the api
+const minSyncInterval = time.Minute
+
+// The sync interval, zero when periodic sync is disabled
+func (c Config) syncInterval() (time.Duration, error) {
+	if c.SyncInterval == "" || c.SyncInterval == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.SyncInterval)
+	if err != nil {
+		return 0, fmt.Errorf("sync_interval: %w", err)
+	}
+	if d < minSyncInterval {
+		return 0, fmt.Errorf("sync_interval: expected 0 or at least %s, got %s", minSyncInterval, c.SyncInterval)
+	}
+	return d, nil
+}
+
+// Loads the config file (the path from the flag, $TODUI_CONFIG or the config dir),
+// then the environment and the flags that were set
+func setupConfig(path, dir string, flags map[string]string) (Config, error) {
+	if path == "" {
+		path = os.Getenv("TODUI_CONFIG")
+	}
+	if path == "" {
+		path = filepath.Join(dir, "config.toml")
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		return config, err
+	}
+	err = config.applyEnv(os.Getenv)
+	if err != nil {
+		return config, err
+	}
+	pathFlags := make([]string, 0)
+	flag.Visit(func(f *flag.Flag) {
+		if name, ok := flags[f.Name]; ok && err == nil {
+			err = config.set(name, f.Value.String())
+		}
+		if f.Name == "t" || f.Name == "d" {
+			pathFlags = append(pathFlags, "-"+f.Name)
+		}
+	})
+	if err != nil {
+		return config, err
+	}
+	if len(pathFlags) > 0 && config.Profile != "" && config.Profile != defaultProfile {
+		return config, fmt.Errorf("%s only applies to the default profile, set token_path and db_path of profile %q in the config file",
+			strings.Join(pathFlags, " and "), config.Profile)
+	}
+	return config, nil
+}
+
+// Base directory from the XDG variable, or the fallback in the home directory
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); dir != "" {
+		return filepath.Join(dir, "todui"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fallback, "todui"), nil
+}
+
+func configDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+func dataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", ".local/share")
+}
+
+func cacheDir() (string, error) {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// The XDG path, unless only the file at the legacy path exists
+func withLegacyPath(path, legacy string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy
+	}
+	return path
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := t.TempDir() + "/config.toml"
+	config, err := loadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, defaultConfig(), config)
+
+	err = os.WriteFile(path, []byte("leader = \",\"\nshow_info = false\n[keys]\ntop = [\"g g\"]\n"), 0644)
+	require.NoError(t, err)
+	config, err = loadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, ",", config.Leader)
+	require.False(t, config.ShowInfo)
+	require.Equal(t, "today", config.DefaultTab)
+	require.Equal(t, []string{"g g"}, config.Keys["top"])
+
+	err = os.WriteFile(path, []byte("colour = 1\n"), 0644)
+	require.NoError(t, err)
+	_, err = loadConfig(path)
+	require.Error(t, err)
+}
+
+func TestConfigSet(t *testing.T) {
+	config := defaultConfig()
+	require.NoError(t, config.set("default_tab", "upcoming"))
+	require.NoError(t, config.set("show_info", "false"))
+	require.NoError(t, config.set("upcoming_days", "14"))
+	require.NoError(t, config.set("board_labels", "todo, doing,done"))
+	require.Equal(t, "upcoming", config.DefaultTab)
+	require.False(t, config.ShowInfo)
+	require.Equal(t, 14, config.UpcomingDays)
+	require.Equal(t, []string{"todo", "doing", "done"}, config.BoardLabels)
+
+	require.Error(t, config.set("show_info", "maybe"))
+	require.Error(t, config.set("nope", "1"))
+	require.Error(t, config.set("keys", "x"))
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	err := os.WriteFile(path, []byte("sort = \"priority\"\neditor = \"nano\"\ndate_format = \"2006-01-02\"\n"), 0644)
+	require.NoError(t, err)
+	t.Setenv("TODUI_EDITOR", "hx")
+	t.Setenv("TODUI_DATE_FORMAT", "02.01.2006")
+	t.Setenv("TODUI_DB_PATH", filepath.Join(dir, "todui.db"))
+
+	config, err := setupConfig(path, dir, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, "priority", config.Sort) // file
+	require.Equal(t, "hx", config.Editor)     // env over file
+	require.Equal(t, "02.01.2006", config.DateFormat)
+	require.Equal(t, filepath.Join(dir, "todui.db"), config.DBPath)
+
+	t.Setenv("TODUI_SHOW_INFO", "yes")
+	_, err = setupConfig(path, dir, map[string]string{})
+	require.Error(t, err)
+}
+
+func TestConfigPathFlags(t *testing.T) {
+	dir := t.TempDir()
+	commandLine := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = commandLine })
+	parse := func(args ...string) (Config, error) {
+		flag.CommandLine = flag.NewFlagSet("todui", flag.ContinueOnError)
+		flag.String("profile", defaultProfile, "")
+		flag.String("t", "", "")
+		flag.String("d", "", "")
+		require.NoError(t, flag.CommandLine.Parse(args))
+		return setupConfig(filepath.Join(dir, "config.toml"), dir, map[string]string{"profile": "profile", "t": "token_path", "d": "db_path"})
+	}
+
+	config, err := parse("-d", "/tmp/todui.db")
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/todui.db", config.DBPath)
+	_, err = parse("--profile", "work", "-t", "/tmp/token")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "-t only applies to the default profile")
+	_, err = parse("--profile", "work")
+	require.NoError(t, err)
+}
+
+func TestParseSettings(t *testing.T) {
+	tab, err := parseTab("calendar")
+	require.NoError(t, err)
+	require.Equal(t, calendarTab, tab)
+	_, err = parseTab("nope")
+	require.Error(t, err)
+
+	_, err = parseSort("random")
+	require.Error(t, err)
+
+	d, err := Config{SyncInterval: "5m"}.syncInterval()
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Minute, d)
+	d, err = defaultConfig().syncInterval()
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), d)
+	_, err = Config{SyncInterval: "often"}.syncInterval()
+	require.Error(t, err)
+	_, err = Config{SyncInterval: "-5m"}.syncInterval()
+	require.Error(t, err)
+	_, err = Config{SyncInterval: "10s"}.syncInterval()
+	require.Error(t, err)
+}
+
+func TestSortTodos(t *testing.T) {
+	todos := []Todo{
+		{Id: "1", ProjectName: "b", Priority: 1, Due: Due{Date: "2023-01-01"}},
+		{Id: "2", ProjectName: "a", Priority: 4, Due: Due{Date: "2023-01-03"}},
+		{Id: "3", ProjectName: "b", Priority: 4, Due: Due{Date: "2023-01-02"}},
+	}
+	ids := func() []string {
+		res := make([]string, len(todos))
+		for i, t := range todos {
+			res[i] = t.Id
+		}
+		return res
+	}
+	sortTodos(todos, sortDue)
+	require.Equal(t, []string{"1", "3", "2"}, ids())
+	sortTodos(todos, sortPriority)
+	require.Equal(t, []string{"3", "2", "1"}, ids())
+	sortTodos(todos, sortProject)
+	require.Equal(t, []string{"2", "1", "3"}, ids())
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Todoist project templates, https://todoist.com/help/articles/format-a-csv-file-to-import-into-todoist
+//
+//	TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE
+//	task,Write report @office,for monday,1,1,,,every friday,en,
+//	task,Draft,,4,2,,,,,
+//	section,Q1,,,,,,,,
+//
+// A template is one project, import it with --project to name it. Priority 1 is p1, and
+// INDENT 2 is a subtask of the task above. Notes are added to the description of their task.
+
+var csvColumns = []string{"TYPE", "CONTENT", "DESCRIPTION", "PRIORITY", "INDENT", "AUTHOR", "RESPONSIBLE", "DATE", "DATE_LANG", "TIMEZONE"}
+
+var errCSVProjects = errors.New("a csv template holds one project, use --filter #Project")
+
+func exportCSV(w io.Writer, data exportData) error {
+	project := ""
+	for _, t := range data.todos {
+		if project != "" && t.ProjectId != project {
+			return errCSVProjects
+		}
+		project = t.ProjectId
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(csvColumns)
+	var write func(t Todo, indent int)
+	write = func(t Todo, indent int) {
+		content := t.Content
+		for _, l := range t.Labels {
+			content += " @" + l
+		}
+		date := t.Due.Date
+		if t.Due.IsRecurring && t.Due.String != "" {
+			date = t.Due.String
+		}
+		lang := t.Due.Lang
+		if lang == "" && date != "" {
+			lang = "en"
+		}
+		cw.Write([]string{"task", content, t.Description, strconv.Itoa(5 - t.Priority), strconv.Itoa(indent), "", "", date, lang, t.Due.Timezone})
+		for _, c := range t.Children {
+			write(c, indent+1)
+		}
+	}
+	for _, t := range data.todos {
+		if t.SectionId == "" {
+			write(t, 1)
+		}
+	}
+	for _, s := range data.sections {
+		if s.ProjectId != project {
+			continue
+		}
+		cw.Write([]string{"section", s.Name, "", "", "", "", "", "", "", ""})
+		for _, t := range data.todos {
+			if t.SectionId == s.Id {
+				write(t, 1)
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func parseCSV(r io.Reader, data exportData) ([]importTask, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"TYPE", "CONTENT"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv: missing %s column", name)
+		}
+	}
+
+	tasks := make([]importTask, 0)
+	// The last task at each indent, to add subtasks and notes to
+	var stack []*importTask
+	section := ""
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csv: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		switch strings.ToLower(field("TYPE")) {
+		case "section":
+			section = field("CONTENT")
+			stack = nil
+		case "note":
+			if len(stack) == 0 {
+				continue
+			}
+			t := stack[len(stack)-1]
+			if t.description != "" {
+				t.description += "\n\n"
+			}
+			t.description += field("CONTENT")
+			t.notes = append(t.notes, "note added to the description")
+		case "task":
+			t := importTask{ref: fmt.Sprintf("line %d", line), section: section, description: field("DESCRIPTION"), priority: 1}
+			t.parseCSVContent(field("CONTENT"))
+			if p, err := strconv.Atoi(field("PRIORITY")); err == nil && p >= 1 && p <= 4 {
+				t.priority = 5 - p
+			}
+			if date := field("DATE"); date != "" {
+				_, dateErr := time.Parse(dueDateLayout, date)
+				_, timeErr := time.Parse(dueFloatingLayout, date)
+				if dateErr == nil || timeErr == nil {
+					t.due.Date = date
+				} else {
+					t.due.String = date
+				}
+			}
+			if field("RESPONSIBLE") != "" {
+				t.notes = append(t.notes, "responsible "+field("RESPONSIBLE")+" not imported")
+			}
+			indent, err := strconv.Atoi(field("INDENT"))
+			if err != nil || indent < 1 {
+				indent = 1
+			}
+			if indent > len(stack)+1 {
+				indent = len(stack) + 1
+			}
+			stack = stack[:indent-1]
+			if len(stack) == 0 {
+				tasks = append(tasks, t)
+				stack = append(stack, &tasks[len(tasks)-1])
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, t)
+				stack = append(stack, &parent.children[len(parent.children)-1])
+			}
+		}
+	}
+	return tasks, nil
+}
+
+func (t *importTask) parseCSVContent(content string) {
+	for _, m := range markdownLabelRegex.FindAllStringSubmatch(content, -1) {
+		t.labels = append(t.labels, m[1])
+	}
+	t.content = strings.Join(strings.Fields(markdownLabelRegex.ReplaceAllString(content, "")), " ")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	data := exportData{
+		sections: []Section{{Id: "21", ProjectId: "11", Name: "Q1"}},
+		todos: []Todo{
+			{Id: "1", ProjectId: "11", Content: "Write report", Description: "for monday, \"really\"", Priority: 4, Labels: []string{"office"},
+				Due:      Due{Date: "2024-01-05", String: "every friday", IsRecurring: true, Lang: "en"},
+				Children: []Todo{{Id: "2", ProjectId: "11", Content: "Draft", Priority: 1, Children: []Todo{{Id: "3", ProjectId: "11", Content: "Outline", Priority: 1}}}}},
+			{Id: "4", ProjectId: "11", SectionId: "21", Content: "Plan", Priority: 2, Due: Due{Date: "2024-01-08", String: "Jan 8"}},
+		},
+	}
+	var b bytes.Buffer
+	require.NoError(t, exportCSV(&b, data))
+	require.Equal(t, `TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE
+task,Write report @office,"for monday, ""really""",1,1,,,every friday,en,
+task,Draft,,4,2,,,,,
+task,Outline,,4,3,,,,,
+section,Q1,,,,,,,,
+task,Plan,,3,1,,,2024-01-08,en,
+`, b.String())
+
+	tasks, err := parseCSV(&b, data)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tasks))
+	require.Equal(t, "Write report", tasks[0].content)
+	require.Equal(t, []string{"office"}, tasks[0].labels)
+	require.Equal(t, 4, tasks[0].priority)
+	require.Equal(t, "every friday", tasks[0].due.String)
+	require.Equal(t, "Draft", tasks[0].children[0].content)
+	require.Equal(t, "Outline", tasks[0].children[0].children[0].content)
+	require.Equal(t, "Plan", tasks[1].content)
+	require.Equal(t, "Q1", tasks[1].section)
+	require.Equal(t, "2024-01-08", tasks[1].due.Date)
+	require.Equal(t, "line 6", tasks[1].ref)
+
+	data.todos[1].ProjectId = "12"
+	require.ErrorIs(t, exportCSV(&b, data), errCSVProjects)
+}
+
+func TestParseCSVTemplate(t *testing.T) {
+	tasks, err := parseCSV(strings.NewReader("\ufefftype,content,priority,indent,responsible\n"+
+		"task,First,,1,\n"+
+		"note,Remember this,,,\n"+
+		"task,Deep,,3,Alex\n"+
+		"\n"+
+		"task,Second,2,1,\n"), exportData{})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tasks))
+	require.Equal(t, "Remember this", tasks[0].description)
+	// Too deep for the task above, so one level down
+	require.Equal(t, "Deep", tasks[0].children[0].content)
+	require.Equal(t, []string{"responsible Alex not imported"}, tasks[0].children[0].notes)
+	require.Equal(t, 3, tasks[1].priority)
+
+	_, err = parseCSV(strings.NewReader("a,b\n"), exportData{})
+	require.Error(t, err)
+}
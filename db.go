@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -56,7 +58,14 @@ create table if not exists project (
  is_archived bit,
  is_deleted bit,
  name text not null,
- parent_id integer
+ parent_id integer,
+ color text
+);
+
+create table if not exists label (
+ id integer primary key,
+ name text not null,
+ color text
 );
 
 create table if not exists item (
@@ -72,11 +81,73 @@ create table if not exists item (
  due_date text,
  due_string text,
  due_timezone text,
- due_lang text
+ due_lang text,
+ section_id text
+);
+
+create table if not exists section (
+ id integer primary key,
+ project_id integer,
+ name text not null,
+ section_order integer
+);
+
+create table if not exists queue (
+ uuid text primary key,
+ type text not null,
+ args text not null,
+ local_due text,
+ created_at text
 )`)
+	if err != nil {
+		return err
+	}
+	return db.migrate()
+}
+
+// Adds columns that are missing in databases created by older versions.
+// New columns are only filled by a full sync, so the sync token is reset.
+func (db DB) migrate() error {
+	columns, err := db.columns("item")
+	if err != nil {
+		return err
+	}
+	if !Contains(columns, "section_id") {
+		_, err = db.conn.Exec(`alter table item add column section_id text;
+delete from synctoken;`)
+		if err != nil {
+			return err
+		}
+	}
+	columns, err = db.columns("project")
+	if err != nil {
+		return err
+	}
+	if !Contains(columns, "color") {
+		_, err = db.conn.Exec(`alter table project add column color text;
+delete from synctoken;`)
+	}
 	return err
 }
 
+func (db DB) columns(table string) ([]string, error) {
+	var columns = make([]string, 0)
+	rows, err := db.conn.Query(`select name from pragma_table_info(?)`, table)
+	if err != nil {
+		return columns, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return columns, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
 func (db DB) InsertFromSync(ctx context.Context, res SyncResponse) error {
 	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
@@ -98,15 +169,173 @@ func (db DB) InsertFromSync(ctx context.Context, res SyncResponse) error {
 		tx.Rollback()
 		return err
 	}
+	err = insertSections(ctx, tx, res.Sections)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = insertLabels(ctx, tx, res.Labels)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 	return tx.Commit()
 }
 
+type SyncCounts struct {
+	Added   int
+	Updated int
+	Removed int
+}
+
+type SyncReport struct {
+	Tasks     SyncCounts
+	Projects  SyncCounts
+	Completed int
+	// Only the latest completed tasks were fetched
+	CompletedTruncated bool
+}
+
+// Replaces everything with the result of a full sync, so rows of tasks and projects
+// deleted in a way the incremental sync missed are removed.
+func (db DB) ReplaceFromSync(ctx context.Context, res SyncResponse, completed []CompletedItem) (SyncReport, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return SyncReport{}, err
+	}
+	report, err := replaceAll(ctx, tx, res, completed)
+	if err != nil {
+		tx.Rollback()
+		return report, err
+	}
+	return report, tx.Commit()
+}
+
+func replaceAll(ctx context.Context, tx *sql.Tx, res SyncResponse, completed []CompletedItem) (SyncReport, error) {
+	var report SyncReport
+	rows, err := tx.QueryContext(ctx, `select `+itemColumns+` from item`)
+	if err != nil {
+		return report, err
+	}
+	items, err := scanItems(rows)
+	if err != nil {
+		return report, err
+	}
+	before := make(map[string]string, len(items))
+	for _, item := range items {
+		before[item.Id] = itemKey(item)
+	}
+	after := make(map[string]string, len(res.Items))
+	for _, item := range res.Items {
+		after[item.Id] = itemKey(item)
+	}
+	report.Tasks = countChanges(before, after)
+
+	rows, err = tx.QueryContext(ctx, `select id, name, coalesce(color, '') from project`)
+	if err != nil {
+		return report, err
+	}
+	before = make(map[string]string)
+	for rows.Next() {
+		var p Project
+		err = rows.Scan(&p.Id, &p.Name, &p.Color)
+		if err != nil {
+			rows.Close()
+			return report, err
+		}
+		before[p.Id] = p.Name + "\x00" + p.Color
+	}
+	rows.Close()
+	after = make(map[string]string, len(res.Projects))
+	for _, p := range res.Projects {
+		after[p.Id] = p.Name + "\x00" + p.Color
+	}
+	report.Projects = countChanges(before, after)
+	report.Completed = len(completed)
+
+	_, err = tx.ExecContext(ctx, `delete from item;
+delete from project;
+delete from section;
+delete from label;
+delete from completed;`)
+	if err != nil {
+		return report, err
+	}
+	query := `replace into synctoken (id, token) values (@id, @token)`
+	_, err = tx.ExecContext(ctx, query, sql.Named("id", 0), sql.Named("token", res.SyncToken))
+	if err != nil {
+		return report, err
+	}
+	err = insertItems(ctx, tx, res.Items)
+	if err != nil {
+		return report, err
+	}
+	err = insertProjects(ctx, tx, res.Projects)
+	if err != nil {
+		return report, err
+	}
+	err = insertSections(ctx, tx, res.Sections)
+	if err != nil {
+		return report, err
+	}
+	err = insertLabels(ctx, tx, res.Labels)
+	if err != nil {
+		return report, err
+	}
+	return report, insertCompleted(ctx, tx, completed)
+}
+
+// The stored fields of an item, to see if it changed
+func itemKey(item Item) string {
+	item.Due.ChangeString = ""
+	labels := strings.Join(item.Labels, ",")
+	item.Labels = nil
+	b, _ := json.Marshal(item)
+	return string(b) + labels
+}
+
+func countChanges(before, after map[string]string) SyncCounts {
+	var c SyncCounts
+	for id, row := range after {
+		old, ok := before[id]
+		switch {
+		case !ok:
+			c.Added++
+		case old != row:
+			c.Updated++
+		}
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			c.Removed++
+		}
+	}
+	return c
+}
+
+func insertCompleted(ctx context.Context, tx *sql.Tx, items []CompletedItem) error {
+	query := `replace into completed (id, content, project_id, completed_at) values (@id, @content, @project_id, @completed_at)`
+	for _, item := range items {
+		_, err := tx.ExecContext(ctx, query,
+			sql.Named("id", item.Id),
+			sql.Named("content", item.Content),
+			sql.Named("project_id", item.ProjectId),
+			sql.Named("completed_at", item.CompletedAt),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func insertItems(ctx context.Context, tx *sql.Tx, items []Item) error {
-	query := `replace into item (id, project_id, content, description, priority, parent_id, checked, due_is_recurring, due_date, due_string, due_timezone, due_lang, labels) values (@id, @projectid, @content, @description, @priority, @parentid, @checked, @due_is_recurring, @due_date, @due_string, @due_timezone, @due_lang, @labels)`
+	query := `replace into item (id, project_id, section_id, content, description, priority, parent_id, checked, due_is_recurring, due_date, due_string, due_timezone, due_lang, labels) values (@id, @projectid, @sectionid, @content, @description, @priority, @parentid, @checked, @due_is_recurring, @due_date, @due_string, @due_timezone, @due_lang, @labels)`
 	for _, item := range items {
 		_, err := tx.ExecContext(ctx, query,
 			sql.Named("id", item.Id),
 			sql.Named("projectid", item.ProjectId), // TODO: handle null!
+			sql.Named("sectionid", item.SectionId),
 			sql.Named("content", item.Content),
 			sql.Named("description", item.Description),
 			sql.Named("priority", item.Priority),
@@ -127,11 +356,57 @@ func insertItems(ctx context.Context, tx *sql.Tx, items []Item) error {
 }
 
 func insertProjects(ctx context.Context, tx *sql.Tx, projects []Project) error {
-	query := `replace into project (id, name) values (@id, @name)`
+	query := `replace into project (id, name, color) values (@id, @name, @color)`
 	for _, project := range projects {
 		_, err := tx.ExecContext(ctx, query,
 			sql.Named("id", project.Id),
 			sql.Named("name", project.Name),
+			sql.Named("color", project.Color),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertSections(ctx context.Context, tx *sql.Tx, sections []Section) error {
+	for _, section := range sections {
+		if section.IsDeleted || section.IsArchived {
+			_, err := tx.ExecContext(ctx, `delete from section where id = @id`, sql.Named("id", section.Id))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		query := `replace into section (id, project_id, name, section_order) values (@id, @project_id, @name, @section_order)`
+		_, err := tx.ExecContext(ctx, query,
+			sql.Named("id", section.Id),
+			sql.Named("project_id", section.ProjectId),
+			sql.Named("name", section.Name),
+			sql.Named("section_order", section.SectionOrder),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertLabels(ctx context.Context, tx *sql.Tx, labels []Label) error {
+	for _, label := range labels {
+		if label.IsDeleted {
+			_, err := tx.ExecContext(ctx, `delete from label where id = @id`, sql.Named("id", label.Id))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		query := `replace into label (id, name, color) values (@id, @name, @color)`
+		_, err := tx.ExecContext(ctx, query,
+			sql.Named("id", label.Id),
+			sql.Named("name", label.Name),
+			sql.Named("color", label.Color),
 		)
 		if err != nil {
 			return err
@@ -166,27 +441,40 @@ func (db DB) getPending(ctx context.Context) (res PendingResponse, err error) {
 	if err != nil {
 		return res, err
 	}
+	sections, err := db.getSections(ctx)
+	if err != nil {
+		return res, err
+	}
 	res.Items = items
 	res.Projects = projects
+	res.Sections = sections
 	return res, err
 }
 
+const itemColumns = `id, project_id, coalesce(section_id, ''), content, description, priority, parent_id, checked, due_string, due_date, due_lang, due_is_recurring, due_timezone, labels`
+
 func (db DB) getPendingItems(ctx context.Context) ([]Item, error) {
-	var items = make([]Item, 0)
-	query := `select id, project_id, content, description, priority, parent_id, due_string, due_date, due_lang, due_is_recurring, due_timezone, labels from item where checked = false`
-	rows, err := db.conn.QueryContext(ctx, query)
+	rows, err := db.conn.QueryContext(ctx, `select `+itemColumns+` from item where checked = false`)
 	if err != nil {
-		return items, err
+		return make([]Item, 0), err
 	}
+	return scanItems(rows)
+}
+
+func scanItems(rows *sql.Rows) ([]Item, error) {
+	defer rows.Close()
+	var items = make([]Item, 0)
 	for rows.Next() {
 		var item Item
 		var labels string
-		err = rows.Scan(&item.Id,
+		err := rows.Scan(&item.Id,
 			&item.ProjectId,
+			&item.SectionId,
 			&item.Content,
 			&item.Description,
 			&item.Priority,
 			&item.ParentId,
+			&item.Checked,
 			&item.Due.String,
 			&item.Due.Date,
 			&item.Due.Lang,
@@ -204,22 +492,24 @@ func (db DB) getPendingItems(ctx context.Context) ([]Item, error) {
 		}
 		items = append(items, item)
 	}
-	return items, nil
+	return items, rows.Err()
 }
 
-// TODO: also fetch from completed table?
+// Completed tasks pulled by a full sync, the latest first
 func (db DB) getCompletedItems(ctx context.Context) ([]CompletedItem, error) {
 	var items = make([]CompletedItem, 0)
-	query := `select id, project_id, content, description item where checked = true`
+	query := `select id, project_id, content, completed_at from completed order by completed_at desc`
 	rows, err := db.conn.QueryContext(ctx, query)
 	if err != nil {
 		return items, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var item CompletedItem
 		err = rows.Scan(&item.Id,
 			&item.ProjectId,
 			&item.Content,
+			&item.CompletedAt,
 		)
 		if err != nil {
 			return items, err
@@ -229,16 +519,54 @@ func (db DB) getCompletedItems(ctx context.Context) ([]CompletedItem, error) {
 	return items, nil
 }
 
+func (db DB) getSections(ctx context.Context) ([]Section, error) {
+	var sections = make([]Section, 0)
+	query := `select id, project_id, name, section_order from section order by section_order`
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return sections, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s Section
+		err = rows.Scan(&s.Id, &s.ProjectId, &s.Name, &s.SectionOrder)
+		if err != nil {
+			return sections, err
+		}
+		sections = append(sections, s)
+	}
+	return sections, nil
+}
+
+func (db DB) getLabels(ctx context.Context) ([]Label, error) {
+	var labels = make([]Label, 0)
+	query := `select id, name, coalesce(color, '') from label order by name`
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return labels, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l Label
+		err = rows.Scan(&l.Id, &l.Name, &l.Color)
+		if err != nil {
+			return labels, err
+		}
+		labels = append(labels, l)
+	}
+	return labels, nil
+}
+
 func (db DB) getProjects(ctx context.Context) ([]Project, error) {
 	var projects = make([]Project, 0)
-	query := `select id, name from project`
+	query := `select id, name, coalesce(color, '') from project`
 	rows, err := db.conn.QueryContext(ctx, query)
 	if err != nil {
 		return projects, err
 	}
 	for rows.Next() {
 		var p Project
-		err = rows.Scan(&p.Id, &p.Name)
+		err = rows.Scan(&p.Id, &p.Name, &p.Color)
 		if err != nil {
 			return projects, err
 		}
@@ -246,3 +574,66 @@ func (db DB) getProjects(ctx context.Context) ([]Project, error) {
 	}
 	return projects, nil
 }
+
+// Commands waiting to be sent to the server, oldest first.
+// local_due is the due date we rolled to locally for recurring tasks.
+type QueuedCommand struct {
+	SyncCommand
+	LocalDue string
+}
+
+func (db DB) enqueue(ctx context.Context, cmd SyncCommand, localDue string) error {
+	args, err := json.Marshal(cmd.Args)
+	if err != nil {
+		return err
+	}
+	query := `insert into queue (uuid, type, args, local_due, created_at) values (@uuid, @type, @args, @local_due, @created_at)`
+	_, err = db.conn.ExecContext(ctx, query,
+		sql.Named("uuid", cmd.Uuid),
+		sql.Named("type", cmd.Type),
+		sql.Named("args", string(args)),
+		sql.Named("local_due", localDue),
+		sql.Named("created_at", time.Now().Format(time.RFC3339)),
+	)
+	return err
+}
+
+func (db DB) getQueue(ctx context.Context) ([]QueuedCommand, error) {
+	var commands = make([]QueuedCommand, 0)
+	query := `select uuid, type, args, local_due from queue order by created_at, rowid`
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return commands, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cmd QueuedCommand
+		var args string
+		err = rows.Scan(&cmd.Uuid, &cmd.Type, &args, &cmd.LocalDue)
+		if err != nil {
+			return commands, err
+		}
+		err = json.Unmarshal([]byte(args), &cmd.Args)
+		if err != nil {
+			return commands, err
+		}
+		commands = append(commands, cmd)
+	}
+	return commands, nil
+}
+
+func (db DB) dequeue(ctx context.Context, uuid string) error {
+	_, err := db.conn.ExecContext(ctx, `delete from queue where uuid = @uuid`, sql.Named("uuid", uuid))
+	return err
+}
+
+func (db DB) setChecked(ctx context.Context, id string) error {
+	_, err := db.conn.ExecContext(ctx, `update item set checked = true where id = @id`, sql.Named("id", id))
+	return err
+}
+
+func (db DB) setDueDate(ctx context.Context, id string, date string) error {
+	query := `update item set due_date = @due_date where id = @id`
+	_, err := db.conn.ExecContext(ctx, query, sql.Named("id", id), sql.Named("due_date", date))
+	return err
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -58,3 +60,194 @@ func newTestDB(t *testing.T) DB {
 	require.NoError(t, err)
 	return db
 }
+
+func TestQueue(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	err := db.InsertFromSync(ctx, SyncResponse{
+		SyncToken: "testing",
+		Items: []Item{{
+			Id:      "1",
+			Content: "recurring",
+			Due:     Due{Date: "2024-01-01", String: "every day", IsRecurring: true},
+		}},
+	})
+	require.NoError(t, err)
+
+	cmd := newSyncCommand("item_close", map[string]interface{}{"id": "1"})
+	err = db.enqueue(ctx, cmd, "2024-01-02")
+	require.NoError(t, err)
+	err = db.setDueDate(ctx, "1", "2024-01-02")
+	require.NoError(t, err)
+
+	queued, err := db.getQueue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(queued))
+	require.Equal(t, "item_close", queued[0].Type)
+	require.Equal(t, "1", queued[0].Args["id"])
+	require.Equal(t, "2024-01-02", queued[0].LocalDue)
+
+	items, err := db.getPendingItems(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-02", items[0].Due.Date)
+
+	err = db.dequeue(ctx, cmd.Uuid)
+	require.NoError(t, err)
+	queued, err = db.getQueue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(queued))
+}
+
+func TestDequeueSynced(t *testing.T) {
+	s := Storage{db: newTestDB(t)}
+	ctx := context.Background()
+	closed := newSyncCommand("item_close", map[string]interface{}{"id": "1"})
+	moved := newSyncCommand("item_move", map[string]interface{}{"id": "2", "project_id": "9"})
+	unknown := newSyncCommand("item_close", map[string]interface{}{"id": "3"})
+	for _, cmd := range []SyncCommand{closed, moved, unknown} {
+		require.NoError(t, s.db.enqueue(ctx, cmd, ""))
+	}
+
+	res := SyncCommandResponse{SyncStatus: map[string]json.RawMessage{
+		closed.Uuid: json.RawMessage(`"ok"`),
+		moved.Uuid:  json.RawMessage(`{"error": "Project not found"}`),
+	}}
+	errs, err := s.dequeueSynced(ctx, []SyncCommand{closed, moved, unknown}, res)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(errs))
+	rejected := rejectedError{errs: errs}
+	require.True(t, isRejected(fmt.Errorf("sync: %w", rejected)))
+	require.Equal(t, "rejected by the server: item_move: Project not found", rejected.Error())
+
+	// Without a status the command is sent again on the next sync
+	queued, err := s.db.getQueue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(queued))
+	require.Equal(t, unknown.Uuid, queued[0].Uuid)
+}
+
+func TestSections(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	err := db.InsertFromSync(ctx, SyncResponse{
+		SyncToken: "testing",
+		Items:     []Item{{Id: "1", ProjectId: "1", SectionId: "10", Content: "in a section"}},
+		Sections: []Section{
+			{Id: "10", ProjectId: "1", Name: "Doing", SectionOrder: 2},
+			{Id: "11", ProjectId: "1", Name: "Todo", SectionOrder: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	res, err := db.getPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "10", res.Items[0].SectionId)
+	require.Equal(t, 2, len(res.Sections))
+	require.Equal(t, "Todo", res.Sections[0].Name)
+
+	err = db.InsertFromSync(ctx, SyncResponse{
+		SyncToken: "testing",
+		Sections:  []Section{{Id: "11", IsDeleted: true}},
+	})
+	require.NoError(t, err)
+	sections, err := db.getSections(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(sections))
+}
+
+func TestLabelsAndColors(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	err := db.InsertFromSync(ctx, SyncResponse{
+		SyncToken: "testing",
+		Projects:  []Project{{Id: "1", Name: "Work", Color: "berry_red"}},
+		Labels: []Label{
+			{Id: "1", Name: "urgent", Color: "red"},
+			{Id: "2", Name: "home", Color: "green"},
+		},
+	})
+	require.NoError(t, err)
+
+	projects, err := db.getProjects(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "berry_red", projects[0].Color)
+
+	err = db.InsertFromSync(ctx, SyncResponse{
+		SyncToken: "testing",
+		Labels:    []Label{{Id: "1", IsDeleted: true}},
+	})
+	require.NoError(t, err)
+	labels, err := db.getLabels(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []Label{{Id: "2", Name: "home", Color: "green"}}, labels)
+}
+
+func TestMigrate(t *testing.T) {
+	path := fmt.Sprintf("testoutput/test-migrate-%d.db", time.Now().UnixNano())
+	old, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = old.Exec(`create table item (id integer primary key, content text);
+create table project (id integer primary key, name text not null);
+create table synctoken (id integer primary key, token text not null);
+insert into synctoken (id, token) values (0, 'incremental');`)
+	require.NoError(t, err)
+	old.Close()
+
+	db, err := NewDB(path)
+	require.NoError(t, err)
+	columns, err := db.columns("item")
+	require.NoError(t, err)
+	require.Contains(t, columns, "section_id")
+	columns, err = db.columns("project")
+	require.NoError(t, err)
+	require.Contains(t, columns, "color")
+
+	// Forces a full sync to get the new columns
+	token, err := db.getToken(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "*", token)
+}
+
+func TestReplaceFromSync(t *testing.T) {
+	db, err := NewDB(fmt.Sprintf("testoutput/test-replace-%d.db", time.Now().UnixNano()))
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+	err = db.InsertFromSync(ctx, SyncResponse{
+		SyncToken: "old",
+		Projects:  []Project{{Id: "11", Name: "Home"}, {Id: "12", Name: "Gone"}},
+		Items: []Item{
+			{Id: "1", ProjectId: "11", Content: "same", Labels: []string{"a"}},
+			{Id: "2", ProjectId: "11", Content: "before"},
+			{Id: "3", ProjectId: "12", Content: "deleted"},
+		},
+	})
+	require.NoError(t, err)
+
+	report, err := db.ReplaceFromSync(ctx, SyncResponse{
+		SyncToken: "new",
+		Projects:  []Project{{Id: "11", Name: "Home"}, {Id: "13", Name: "New"}},
+		Items: []Item{
+			{Id: "1", ProjectId: "11", Content: "same", Labels: []string{"a"}},
+			{Id: "2", ProjectId: "11", Content: "after"},
+			{Id: "4", ProjectId: "13", Content: "added"},
+		},
+	}, []CompletedItem{{Id: "100", TaskId: "5", ProjectId: "11", Content: "done", CompletedAt: "2024-01-01T10:00:00Z"}})
+	require.NoError(t, err)
+	require.Equal(t, SyncCounts{Added: 1, Updated: 1, Removed: 1}, report.Tasks)
+	require.Equal(t, SyncCounts{Added: 1, Updated: 0, Removed: 1}, report.Projects)
+	require.Equal(t, 1, report.Completed)
+
+	items, err := db.getPendingItems(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(items))
+	projects, err := db.getProjects(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(projects))
+	completed, err := db.getCompletedItems(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "done", completed[0].Content)
+	token, err := db.getToken(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "new", token)
+}
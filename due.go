@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Todoist due dates comes in three flavours:
+// - full-day:                "2016-12-01"
+// - floating (local) time:   "2016-12-03T12:00:00"
+// - fixed timezone (UTC):    "2016-12-06T13:00:00Z" with due.timezone set to e.g. "Europe/Oslo"
+//
+// https://developer.todoist.com/sync/v9/#due-dates
+
+const (
+	dueDateLayout     = "2006-01-02"
+	dueFloatingLayout = "2006-01-02T15:04:05"
+	dueFixedLayout    = "2006-01-02T15:04:05Z07:00"
+)
+
+// Parses the due date. Fixed timezone dates are returned in their own timezone,
+// floating and full-day dates in local time.
+func parseDueDate(date string, timezone string) (t time.Time, hasTime bool, err error) {
+	loc := time.Local
+	if timezone != "" {
+		if l, err := time.LoadLocation(timezone); err == nil {
+			loc = l
+		}
+	}
+	if t, err = time.Parse(dueFixedLayout, date); err == nil {
+		return t.In(loc), true, nil
+	}
+	if t, err = time.ParseInLocation(dueFloatingLayout, date, loc); err == nil {
+		return t, true, nil
+	}
+	if t, err = time.ParseInLocation(dueDateLayout, date, time.Local); err == nil {
+		return t, false, nil
+	}
+	return t, false, fmt.Errorf("could not parse due date: %s", date)
+}
+
+// Formats t the same way as the original due date
+func formatDueDate(t time.Time, original string, hasTime bool) string {
+	if !hasTime {
+		return t.Format(dueDateLayout)
+	}
+	if _, err := time.Parse(dueFixedLayout, original); err == nil {
+		return t.UTC().Format(dueFixedLayout)
+	}
+	return t.Format(dueFloatingLayout)
+}
+
+// Due date in local time
+func (d Due) Time() (t time.Time, hasTime bool, err error) {
+	t, hasTime, err = parseDueDate(d.Date, d.Timezone)
+	return t.Local(), hasTime, err
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Formats due dates relative to today, e.g. "Tomorrow", "Friday" or "in 2 weeks".
+// Dates too far away to make sense as relative are shown with absoluteFormat.
+type relativeDateFormatter struct {
+	now            func() time.Time
+	absoluteFormat string
+}
+
+var dateFormatter = relativeDateFormatter{
+	now:            time.Now,
+	absoluteFormat: "02/01/2006",
+}
+
+// Whole calendar days from a to b, in a's timezone. Not affected by DST.
+func daysBetween(a, b time.Time) int {
+	b = b.In(a.Location())
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+func (f relativeDateFormatter) format(t time.Time, hasTime bool) string {
+	now := f.now()
+	t = t.In(now.Location())
+	days := daysBetween(now, t)
+	var result string
+	switch {
+	case days == 0:
+		result = "Today"
+	case days == 1:
+		result = "Tomorrow"
+	case days == -1:
+		result = "Yesterday"
+	case days > 1 && days < 7:
+		result = t.Weekday().String()
+	case days >= 7 && days < 28:
+		weeks := days / 7
+		if weeks == 1 {
+			result = "in 1 week"
+		} else {
+			result = fmt.Sprintf("in %d weeks", weeks)
+		}
+	default:
+		result = t.Format(f.absoluteFormat)
+	}
+	if hasTime {
+		result += " " + t.Format("15:04")
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDueDate(t *testing.T) {
+	d, hasTime, err := parseDueDate("2024-01-05", "")
+	require.NoError(t, err)
+	require.False(t, hasTime)
+	require.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.Local), d)
+
+	d, hasTime, err = parseDueDate("2024-01-05T14:00:00", "")
+	require.NoError(t, err)
+	require.True(t, hasTime)
+	require.Equal(t, time.Date(2024, 1, 5, 14, 0, 0, 0, time.Local), d)
+
+	d, hasTime, err = parseDueDate("2024-01-05T13:00:00Z", "Europe/Oslo")
+	require.NoError(t, err)
+	require.True(t, hasTime)
+	require.Equal(t, "Europe/Oslo", d.Location().String())
+	require.Equal(t, 14, d.Hour())
+
+	_, _, err = parseDueDate("tomorrow", "")
+	require.Error(t, err)
+}
+
+func TestFormatDueDate(t *testing.T) {
+	d := time.Date(2024, 1, 5, 14, 0, 0, 0, time.UTC)
+	require.Equal(t, "2024-01-05", formatDueDate(d, "2024-01-04", false))
+	require.Equal(t, "2024-01-05T14:00:00", formatDueDate(d, "2024-01-04T14:00:00", true))
+	require.Equal(t, "2024-01-05T14:00:00Z", formatDueDate(d, "2024-01-04T14:00:00Z", true))
+}
+
+func TestOverdue(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour * 48)
+
+	todo := Todo{Due: Due{Date: past.Format(dueFloatingLayout)}}
+	require.True(t, todo.Overdue())
+	require.True(t, todo.DueTodayOrBefore())
+
+	todo = Todo{Due: Due{Date: past.UTC().Format(dueFixedLayout), Timezone: "America/New_York"}}
+	require.True(t, todo.Overdue())
+
+	// Full-day tasks due today are not overdue
+	todo = Todo{Due: Due{Date: now.Format(dueDateLayout)}}
+	require.False(t, todo.Overdue())
+	require.True(t, todo.DueTodayOrBefore())
+
+	todo = Todo{Due: Due{Date: future.Format(dueFloatingLayout)}}
+	require.False(t, todo.Overdue())
+	require.False(t, todo.DueTodayOrBefore())
+}
+
+func TestSortByDueDatetime(t *testing.T) {
+	todos := []Todo{
+		{Id: "no due", Priority: 4},
+		{Id: "afternoon", Due: Due{Date: "2024-01-05T14:00:00"}},
+		{Id: "morning", Due: Due{Date: "2024-01-05T09:00:00"}},
+		{Id: "full day", Due: Due{Date: "2024-01-05"}},
+		{Id: "day before", Due: Due{Date: "2024-01-04T23:00:00"}},
+	}
+	sort.Sort(ByDueThenPriority(todos))
+	ids := make([]string, 0)
+	for _, t := range todos {
+		ids = append(ids, t.Id)
+	}
+	require.Equal(t, []string{"day before", "full day", "morning", "afternoon", "no due"}, ids)
+}
+
+func TestEditRequestDue(t *testing.T) {
+	old := Due{Date: "2024-01-05T09:00:00"}
+	// Unchanged, the floating time is left alone
+	req := newEditRequest(Todo{Content: "Call mom", Due: old}, old)
+	require.Equal(t, EditRequest{Content: "Call mom", Labels: &[]string{}}, req)
+
+	// Moved, and still floating
+	req = newEditRequest(Todo{Due: Due{Date: "2024-01-06T09:00:00"}}, old)
+	require.Equal(t, "2024-01-06 09:00", req.DueString)
+	require.Empty(t, req.DueDatetime)
+
+	// A fixed time stays in its timezone
+	fixed := Due{Date: "2024-01-06T08:00:00Z", Timezone: "Europe/Oslo"}
+	req = newEditRequest(Todo{Due: fixed}, old)
+	require.Equal(t, "2024-01-06T08:00:00Z", req.DueDatetime)
+	require.Empty(t, req.DueString)
+
+	req = newEditRequest(Todo{Due: Due{Date: "2024-01-06"}}, old)
+	require.Equal(t, "2024-01-06", req.Due)
+}
+
+func TestRelativeDateFormatter(t *testing.T) {
+	f := relativeDateFormatter{
+		now: func() time.Time {
+			return time.Date(2024, 1, 31, 22, 0, 0, 0, time.Local)
+		},
+		absoluteFormat: "2006-01-02",
+	}
+	cases := []struct {
+		due      time.Time
+		hasTime  bool
+		expected string
+	}{
+		{time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local), false, "Today"},
+		// Across the month boundary
+		{time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local), false, "Tomorrow"},
+		{time.Date(2024, 1, 30, 0, 0, 0, 0, time.Local), false, "Yesterday"},
+		{time.Date(2024, 2, 2, 0, 0, 0, 0, time.Local), false, "Friday"},
+		{time.Date(2024, 2, 6, 0, 0, 0, 0, time.Local), false, "Tuesday"},
+		{time.Date(2024, 2, 7, 0, 0, 0, 0, time.Local), false, "in 1 week"},
+		{time.Date(2024, 2, 15, 0, 0, 0, 0, time.Local), false, "in 2 weeks"},
+		{time.Date(2024, 2, 28, 0, 0, 0, 0, time.Local), false, "2024-02-28"},
+		{time.Date(2024, 1, 20, 0, 0, 0, 0, time.Local), false, "2024-01-20"},
+		// The 31st next month is not today
+		{time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local), false, "2024-03-31"},
+		{time.Date(2024, 2, 1, 9, 30, 0, 0, time.Local), true, "Tomorrow 09:30"},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.expected, f.format(c.due, c.hasTime), c.due.String())
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	a := time.Date(2024, 12, 31, 23, 59, 0, 0, time.Local)
+	b := time.Date(2025, 1, 1, 0, 1, 0, 0, time.Local)
+	require.Equal(t, 1, daysBetween(a, b))
+	require.Equal(t, -1, daysBetween(b, a))
+	require.Equal(t, 0, daysBetween(a, a))
+}
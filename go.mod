@@ -3,11 +3,13 @@ module todui
 go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/charmbracelet/bubbles v0.16.1
 	github.com/charmbracelet/bubbletea v0.24.2
 	github.com/charmbracelet/lipgloss v0.9.1
 	github.com/gomarkdown/markdown v0.0.0-20231115200524-a660076da3fd
 	github.com/mattn/go-sqlite3 v1.14.16
+	github.com/sahilm/fuzzy v0.1.1
 	github.com/stretchr/testify v1.8.1
 )
 
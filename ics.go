@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// iCalendar, https://www.rfc-editor.org/rfc/rfc5545
+//
+// Tasks are VTODOs, and with --events also all-day VEVENTs on their due dates, for calendars without tasks.
+// Recurring due strings the recurrence parser understands get an RRULE.
+// `todui serve-ics` serves the same feed from the local db, for calendar apps to subscribe to.
+
+const (
+	icsDateLayout     = "20060102"
+	icsFloatingLayout = "20060102T150405"
+	icsUTCLayout      = "20060102T150405Z"
+)
+
+func exportICS(w io.Writer, data exportData) error {
+	bw := bufio.NewWriter(w)
+	ics := icsWriter{w: bw}
+	stamp := dateFormatter.now().UTC().Format(icsUTCLayout)
+	ics.line("BEGIN:VCALENDAR")
+	ics.line("VERSION:2.0")
+	ics.line("PRODID:-//todui//todui//EN")
+	ics.line("CALSCALE:GREGORIAN")
+	ics.line("X-WR-CALNAME:todui")
+	var write func(t Todo, parent string)
+	write = func(t Todo, parent string) {
+		ics.todo(t, parent, stamp, "")
+		if data.events && t.Due.Date != "" {
+			ics.event(t, stamp)
+		}
+		for _, c := range t.Children {
+			write(c, t.Id)
+		}
+	}
+	for _, t := range data.todos {
+		write(t, "")
+	}
+	for _, t := range data.completed {
+		ics.todo(t.Todo, "", stamp, t.CompletedAt)
+	}
+	ics.line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+type icsWriter struct {
+	w *bufio.Writer
+}
+
+// Writes a content line, folded at 75 octets without splitting utf-8 characters
+func (ics icsWriter) line(s string) {
+	for len(s) > 75 {
+		i := 75
+		for i > 0 && s[i]&0xC0 == 0x80 {
+			i--
+		}
+		ics.w.WriteString(s[:i] + "\r\n")
+		s = " " + s[i:]
+	}
+	ics.w.WriteString(s + "\r\n")
+}
+
+func (ics icsWriter) property(name, value string) {
+	ics.line(name + ":" + value)
+}
+
+func (ics icsWriter) text(name, value string) {
+	if value != "" {
+		ics.line(name + ":" + icsEscape(value))
+	}
+}
+
+func (ics icsWriter) todo(t Todo, parent, stamp, completedAt string) {
+	ics.line("BEGIN:VTODO")
+	ics.property("UID", t.Id+"@todui")
+	ics.property("DTSTAMP", stamp)
+	ics.text("SUMMARY", t.Content)
+	ics.text("DESCRIPTION", t.Description)
+	if len(t.Labels) > 0 {
+		labels := make([]string, len(t.Labels))
+		for i, l := range t.Labels {
+			labels[i] = icsEscape(l)
+		}
+		ics.property("CATEGORIES", strings.Join(labels, ","))
+	}
+	ics.text("X-TODOIST-PROJECT", t.ProjectName)
+	if p := icsPriority(t.Priority); p != 0 {
+		ics.property("PRIORITY", fmt.Sprint(p))
+	}
+	if value, ok := icsDate(t.Due); ok {
+		// A DUE must be later than the DTSTART, so recurring tasks only have the DTSTART the RRULE repeats
+		if rrule, ok := icsRRule(t.Due); ok {
+			ics.line("DTSTART" + value)
+			ics.property("RRULE", rrule)
+		} else {
+			ics.line("DUE" + value)
+		}
+	}
+	if parent != "" {
+		ics.property("RELATED-TO", parent+"@todui")
+	}
+	if t.Checked {
+		ics.property("STATUS", "COMPLETED")
+		if c, err := time.Parse(time.RFC3339, completedAt); err == nil {
+			ics.property("COMPLETED", c.UTC().Format(icsUTCLayout))
+		}
+	} else {
+		ics.property("STATUS", "NEEDS-ACTION")
+	}
+	ics.line("END:VTODO")
+}
+
+// An all-day event on the due date
+func (ics icsWriter) event(t Todo, stamp string) {
+	due, _, err := t.Due.Time()
+	if err != nil {
+		return
+	}
+	ics.line("BEGIN:VEVENT")
+	ics.property("UID", t.Id+"-event@todui")
+	ics.property("DTSTAMP", stamp)
+	ics.text("SUMMARY", t.Content)
+	ics.text("DESCRIPTION", t.Description)
+	ics.property("DTSTART;VALUE=DATE", due.Format(icsDateLayout))
+	ics.property("DTEND;VALUE=DATE", due.AddDate(0, 0, 1).Format(icsDateLayout))
+	if rrule, ok := icsRRule(t.Due); ok {
+		ics.property("RRULE", rrule)
+	}
+	ics.property("TRANSP", "TRANSPARENT")
+	ics.line("END:VEVENT")
+}
+
+// The parameters and value of a DUE or DTSTART property, like ";VALUE=DATE:20240131"
+func icsDate(due Due) (string, bool) {
+	if due.Date == "" {
+		return "", false
+	}
+	if t, err := time.Parse(dueFixedLayout, due.Date); err == nil {
+		return ":" + t.UTC().Format(icsUTCLayout), true
+	}
+	if t, err := time.Parse(dueFloatingLayout, due.Date); err == nil {
+		return ":" + t.Format(icsFloatingLayout), true
+	}
+	if t, err := time.Parse(dueDateLayout, due.Date); err == nil {
+		return ";VALUE=DATE:" + t.Format(icsDateLayout), true
+	}
+	return "", false
+}
+
+func icsRRule(due Due) (string, bool) {
+	if !due.IsRecurring {
+		return "", false
+	}
+	r, err := parseRecurrence(due.String)
+	if err != nil {
+		return "", false
+	}
+	return r.rrule()
+}
+
+// 1 is the highest priority, 0 is undefined
+func icsPriority(p int) int {
+	switch p {
+	case 4:
+		return 1
+	case 3:
+		return 5
+	case 2:
+		return 9
+	}
+	return 0
+}
+
+func icsEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\r\n", "\\n", "\n", "\\n").Replace(s)
+}
+
+// Serves the feed of the local db. The ?filter=, ?events= and ?completed= query parameters
+// works like the flags, so one calendar can subscribe to a single project.
+func (c cli) icsHandler(events bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/todui.ics" {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := c.exportData(true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Query().Get("completed") != "true" {
+			data.completed = nil
+		}
+		data.filter(r.URL.Query().Get("filter"))
+		data.events = events || r.URL.Query().Get("events") == "true"
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		exportICS(w, data)
+	}
+}
+
+func (c cli) serveICS(args []string) error {
+	fs := c.flagSet("serve-ics")
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on.")
+	events := fs.Bool("events", false, "Also serve all-day events on the due dates.")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("unexpected argument %q", fs.Arg(0))
+	}
+	interval, err := c.config.syncInterval()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if interval > 0 {
+		go c.syncEvery(ctx, interval)
+	}
+	server := &http.Server{Addr: *addr, Handler: localOnly(c.icsHandler(*events))}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	fmt.Fprintf(c.stdout, "serving http://%s/todui.ics\n", *addr)
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Keeps the local db up to date with the sync interval of the config file
+func (c cli) syncEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.storage.fetchTodos(); err != nil {
+				fmt.Fprintf(c.stderr, "sync: %s\n", err)
+			}
+		}
+	}
+}
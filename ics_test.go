@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRRule(t *testing.T) {
+	tests := map[string]string{
+		"every day":            "FREQ=DAILY",
+		"every other week":     "FREQ=WEEKLY;INTERVAL=2",
+		"every mon, fri at 9":  "FREQ=WEEKLY;BYDAY=MO,FR",
+		"every weekday":        "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		"every 15th":           "FREQ=MONTHLY;BYMONTHDAY=15",
+		"every last day":       "FREQ=MONTHLY;BYMONTHDAY=-1",
+		"every 3 months":       "FREQ=MONTHLY;INTERVAL=3",
+		"every jan 5":          "FREQ=YEARLY;BYMONTH=1;BYMONTHDAY=5",
+		"every! 2 days":        "",
+		"every day until june": "",
+	}
+	for s, expected := range tests {
+		rrule, ok := icsRRule(Due{String: s, IsRecurring: true})
+		require.Equal(t, expected != "", ok, s)
+		require.Equal(t, expected, rrule, s)
+	}
+}
+
+func TestExportICS(t *testing.T) {
+	now := dateFormatter.now
+	dateFormatter.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { dateFormatter.now = now }()
+
+	var b bytes.Buffer
+	err := exportICS(&b, exportData{
+		events: true,
+		todos: []Todo{{
+			Id:          "1",
+			Content:     "Weekly review, " + strings.Repeat("long ", 20),
+			Description: "line one\nline two",
+			Labels:      []string{"work"},
+			Priority:    4,
+			Due:         Due{Date: "2024-01-05", String: "every friday", IsRecurring: true},
+			Children:    []Todo{{Id: "2", Content: "Inbox zero", Due: Due{Date: "2024-01-05T10:00:00Z"}}},
+		}},
+	})
+	require.NoError(t, err)
+	s := b.String()
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(line), 75)
+	}
+	unfolded := strings.ReplaceAll(s, "\r\n ", "")
+	require.Contains(t, unfolded, "BEGIN:VTODO\r\nUID:1@todui\r\nDTSTAMP:20240101T120000Z\r\nSUMMARY:Weekly review\\, long")
+	require.Contains(t, unfolded, "DESCRIPTION:line one\\nline two\r\nCATEGORIES:work\r\nPRIORITY:1\r\n")
+	require.Contains(t, unfolded, "DTSTART;VALUE=DATE:20240105\r\nRRULE:FREQ=WEEKLY;BYDAY=FR\r\nSTATUS:NEEDS-ACTION\r\n")
+	// Only the task without a recurrence has a DUE, which must not equal its DTSTART
+	require.Equal(t, 1, strings.Count(unfolded, "\r\nDUE"))
+	require.Contains(t, unfolded, "UID:2@todui\r\n")
+	require.Contains(t, unfolded, "DUE:20240105T100000Z\r\nRELATED-TO:1@todui\r\nSTATUS:NEEDS-ACTION\r\n")
+	require.Contains(t, unfolded, "BEGIN:VEVENT\r\nUID:1-event@todui\r\n")
+	require.Equal(t, 2, strings.Count(unfolded, "BEGIN:VEVENT"))
+	require.True(t, strings.HasSuffix(s, "END:VCALENDAR\r\n"))
+}
+
+func TestServeICS(t *testing.T) {
+	c, _, _ := newTestCLI(t)
+	_, err := c.storage.db.ReplaceFromSync(context.Background(), SyncResponse{
+		SyncToken: "testing",
+		Projects:  []Project{{Id: "11", Name: "Home"}, {Id: "12", Name: "Work"}},
+		Items: []Item{
+			{Id: "1", ProjectId: "11", Content: "Buy milk", Due: Due{Date: "2024-01-02"}},
+			{Id: "2", ProjectId: "12", Content: "Write report", Due: Due{Date: "2024-01-01"}},
+		},
+	}, []CompletedItem{{Id: "100", TaskId: "5", ProjectId: "11", Content: "Buy bread", CompletedAt: "2024-01-01T10:00:00Z"}})
+	require.NoError(t, err)
+	server := httptest.NewServer(localOnly(c.icsHandler(false)))
+	defer server.Close()
+
+	res, err := server.Client().Get(server.URL + "/todui.ics?filter=%23Home")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, 200, res.StatusCode)
+	require.Equal(t, "text/calendar; charset=utf-8", res.Header.Get("Content-Type"))
+	var b bytes.Buffer
+	b.ReadFrom(res.Body)
+	require.Contains(t, b.String(), "SUMMARY:Buy milk")
+	require.NotContains(t, b.String(), "Write report")
+	// Completed tasks only when asked for, like export --completed
+	require.NotContains(t, b.String(), "Buy bread")
+
+	res, err = server.Client().Get(server.URL + "/todui.ics?filter=%23Home&completed=true")
+	require.NoError(t, err)
+	b.Reset()
+	b.ReadFrom(res.Body)
+	res.Body.Close()
+	require.Contains(t, b.String(), "SUMMARY:Buy bread")
+
+	res, err = server.Client().Get(server.URL + "/nope")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, 404, res.StatusCode)
+
+	// DNS rebinding
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/todui.ics", nil)
+	require.NoError(t, err)
+	req.Host = "evil.example.com"
+	res, err = server.Client().Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, 403, res.StatusCode)
+}
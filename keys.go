@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Every binding in keyMap can be remapped in the config file, by its snake cased name:
+//
+//	leader = "space"
+//
+//	[keys]
+//	top = ["g g", "home"]
+//	sync = ["<leader> s"]
+//	done = []  # unbound
+//
+// A binding is a list of key sequences. The keys of a sequence are separated by spaces,
+// and "<leader>" is replaced by the leader key.
+
+const leaderKey = "<leader>"
+
+// Bindings that are used in each view. Tabs with their own keys also use the list keys,
+// so a key sequence must be unique (and not the prefix of another one) in each of them.
+type keyContext struct {
+	name     string
+	bindings []string
+	list     bool // also uses the list bindings
+}
+
+var (
+	listBindings = []string{"up", "down", "top", "bottom", "page_up", "page_down", "half_page_up", "half_page_down",
+		"inbox_tab", "today_tab", "all_tasks_tab", "completed_tab", "upcoming_tab", "calendar_tab", "board_tab",
+		"sync", "new", "new_with_editor", "edit", "done", "filter", "info", "switch_profile", "command_palette", "help", "quit"}
+
+	keyContexts = []keyContext{
+		{name: "list", bindings: listBindings},
+		{name: "upcoming", bindings: []string{"next_day", "prev_day", "focus_next_day", "focus_prev_day", "reschedule", "empty_days"}, list: true},
+		{name: "calendar", bindings: []string{"left", "right", "up", "down", "prev_month", "next_month", "go_to_today", "select", "exit_input", "reschedule"}, list: true},
+		{name: "board", bindings: []string{"left", "right", "up", "down", "move_card_left", "move_card_right", "prev_project", "next_project", "board_mode"}, list: true},
+		{name: "input", bindings: []string{"set_input", "clear_input", "exit_input", "complete", "next_suggestion", "prev_suggestion"}},
+	}
+)
+
+// Config name of a keyMap field, e.g. AllTasksTab is all_tasks_tab
+func bindingName(field string) string {
+	var b strings.Builder
+	for i, r := range field {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Pointers to all the bindings, by config name
+func (k *keyMap) bindings() map[string]*key.Binding {
+	bindings := make(map[string]*key.Binding)
+	v := reflect.ValueOf(k).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		bindings[bindingName(v.Type().Field(i).Name)] = v.Field(i).Addr().Interface().(*key.Binding)
+	}
+	return bindings
+}
+
+// The tab bindings, indexed by Tab
+func (k keyMap) tabKeys() []key.Binding {
+	return []key.Binding{k.InboxTab, k.TodayTab, k.AllTasksTab, k.CompletedTab, k.UpcomingTab, k.CalendarTab, k.BoardTab}
+}
+
+// Bindings of the context, with the list bindings last
+func (k keyMap) context(c keyContext) []key.Binding {
+	all := k.bindings()
+	names := c.bindings
+	if c.list {
+		names = append(append([]string{}, names...), listBindings...)
+	}
+	bindings := make([]key.Binding, 0, len(names))
+	seen := make(map[string]bool)
+	for _, n := range names {
+		if !seen[n] {
+			seen[n] = true
+			bindings = append(bindings, *all[n])
+		}
+	}
+	return bindings
+}
+
+// Normalizes a key sequence from the config file
+func parseKeySequence(seq, leader string) (string, error) {
+	keys := strings.Fields(seq)
+	if len(keys) == 0 {
+		return "", fmt.Errorf("empty key sequence")
+	}
+	for i, k := range keys {
+		if k == leaderKey {
+			if leader == "" {
+				return "", fmt.Errorf("%q uses %s, but no leader key is set", seq, leaderKey)
+			}
+			k = leader
+		}
+		if k == " " {
+			k = "space"
+		}
+		keys[i] = k
+	}
+	return strings.Join(keys, " "), nil
+}
+
+// How a key is shown in the help bar
+func keyHelp(k string) string {
+	switch k {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	}
+	return k
+}
+
+// Sets the help of every binding from its keys
+func (k *keyMap) updateHelp() {
+	for _, b := range k.bindings() {
+		if !b.Enabled() {
+			continue
+		}
+		help := make([]string, 0, len(b.Keys()))
+		for _, seq := range b.Keys() {
+			help = append(help, keyHelp(seq))
+		}
+		b.SetHelp(strings.Join(help, "/"), b.Help().Desc)
+	}
+}
+
+// Returns a copy of the keys with the bindings from the config replaced
+func (k keyMap) remap(leader string, config map[string][]string) (keyMap, error) {
+	bindings := k.bindings()
+	for name, seqs := range config {
+		b, ok := bindings[name]
+		if !ok {
+			return k, fmt.Errorf("unknown key binding %q", name)
+		}
+		if len(seqs) == 0 {
+			b.Unbind()
+			continue
+		}
+		keys := make([]string, 0, len(seqs))
+		for _, s := range seqs {
+			seq, err := parseKeySequence(s, leader)
+			if err != nil {
+				return k, fmt.Errorf("key binding %q: %w", name, err)
+			}
+			keys = append(keys, seq)
+		}
+		desc := b.Help().Desc
+		*b = key.NewBinding(key.WithKeys(keys...), key.WithHelp("", desc))
+	}
+	k.updateHelp()
+	return k, k.validate()
+}
+
+// Checks that no key sequence is used twice, or is the prefix of another one, in any context
+func (k keyMap) validate() error {
+	all := k.bindings()
+	for _, c := range keyContexts {
+		names := c.bindings
+		if c.list {
+			names = append(append([]string{}, names...), listBindings...)
+		}
+		used := make(map[string]string)
+		for _, n := range names {
+			for _, seq := range all[n].Keys() {
+				if c.name == "input" && strings.Contains(seq, " ") {
+					return fmt.Errorf("key binding %q: key sequences can not be used in input fields", n)
+				}
+				if other, ok := used[seq]; ok && other != n {
+					return fmt.Errorf("key binding %q and %q both use %q in the %s view", other, n, seq, c.name)
+				}
+				used[seq] = n
+			}
+		}
+		for seq, n := range used {
+			for other, o := range used {
+				if strings.HasPrefix(other, seq+" ") {
+					return fmt.Errorf("key binding %q uses %q, which is the start of %q used by %q in the %s view", n, seq, other, o, c.name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func keyContextNamed(name string) keyContext {
+	for _, c := range keyContexts {
+		if c.name == name {
+			return c
+		}
+	}
+	return keyContexts[0]
+}
+
+// The context of the current tab
+func (m model) keyContext() keyContext {
+	switch m.tab {
+	case upcomingTab:
+		return keyContextNamed("upcoming")
+	case calendarTab:
+		return keyContextNamed("calendar")
+	case boardTab:
+		return keyContextNamed("board")
+	}
+	return keyContextNamed("list")
+}
+
+// Adds the key to the pending key sequence. Returns the completed sequence as a key message,
+// or false while waiting for more keys.
+func (m *model) keySequence(msg tea.KeyMsg) (tea.KeyMsg, bool) {
+	k := msg.String()
+	if k == " " {
+		k = "space"
+	}
+	seq := strings.Join(append(append([]string{}, m.pendingKeys...), k), " ")
+	pending := len(m.pendingKeys) > 0
+	m.pendingKeys = nil
+
+	bindings := m.keys.context(m.keyContext())
+	for _, b := range bindings {
+		for _, s := range b.Keys() {
+			if strings.HasPrefix(s, seq+" ") && b.Enabled() {
+				m.pendingKeys = strings.Fields(seq)
+				return msg, false
+			}
+		}
+	}
+	if pending && !key.Matches(sequenceMsg(seq), bindings...) {
+		// Not a known sequence, start over from the last key
+		return m.keySequence(msg)
+	}
+	if !pending && k != "space" {
+		return msg, true
+	}
+	return sequenceMsg(seq), true
+}
+
+// A key message that matches the bindings of the key sequence
+func sequenceMsg(seq string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(seq)}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindingName(t *testing.T) {
+	require.Equal(t, "up", bindingName("Up"))
+	require.Equal(t, "all_tasks_tab", bindingName("AllTasksTab"))
+	require.Equal(t, "half_page_down", bindingName("HalfPageDown"))
+}
+
+func TestDefaultKeysAreValid(t *testing.T) {
+	require.NoError(t, keys.validate())
+	// Every binding in the contexts exists
+	all := keys.bindings()
+	for _, c := range keyContexts {
+		for _, n := range c.bindings {
+			require.Contains(t, all, n)
+		}
+	}
+}
+
+func TestRemap(t *testing.T) {
+	k, err := keys.remap("space", map[string][]string{
+		"top":  {"g g", "home"},
+		"sync": {"<leader> s"},
+		"done": {},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"g g", "home"}, k.Top.Keys())
+	require.Equal(t, "g g/home", k.Top.Help().Key)
+	require.Equal(t, "to the top", k.Top.Help().Desc)
+	require.Equal(t, []string{"space s"}, k.Sync.Keys())
+	require.False(t, k.Done.Enabled())
+	// The defaults are not changed
+	require.Equal(t, []string{"g"}, keys.Top.Keys())
+
+	_, err = keys.remap("space", map[string][]string{"nope": {"x"}})
+	require.Error(t, err)
+	_, err = keys.remap("", map[string][]string{"sync": {"<leader> s"}})
+	require.Error(t, err)
+}
+
+func TestRemapConflicts(t *testing.T) {
+	// Same key in the list view
+	_, err := keys.remap("space", map[string][]string{"sync": {"n"}})
+	require.Error(t, err)
+	// Prefix of another sequence
+	_, err = keys.remap("space", map[string][]string{"bottom": {"g g"}})
+	require.Error(t, err)
+	// Conflicts with a list key in the board tab
+	_, err = keys.remap("space", map[string][]string{"board_mode": {"s"}})
+	require.Error(t, err)
+	// Tabs does not conflict with each other
+	_, err = keys.remap("space", map[string][]string{"board_mode": {"E"}})
+	require.NoError(t, err)
+	// No sequences in input fields
+	_, err = keys.remap("space", map[string][]string{"set_input": {"a b"}})
+	require.Error(t, err)
+}
+
+func TestKeySequence(t *testing.T) {
+	m := newScrollModel(100)
+	k, err := keys.remap("space", map[string][]string{
+		"top":    {"g g"},
+		"bottom": {"space G"},
+	})
+	require.NoError(t, err)
+	m.keys = k
+
+	m = press(m, "j", "j", "j")
+	require.Equal(t, 3, m.cursor.index)
+	m = press(m, "g")
+	require.Equal(t, []string{"g"}, m.pendingKeys)
+	require.Contains(t, m.View(), "g …")
+	m = press(m, "g")
+	require.Equal(t, 0, m.cursor.index)
+	require.Empty(t, m.pendingKeys)
+
+	var res tea.Model = m
+	res, _ = res.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	res, _ = res.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'G'}})
+	require.Equal(t, 99, res.(model).cursor.index)
+
+	// An unknown sequence starts over from the last key
+	m = press(res.(model), "g", "k")
+	require.Equal(t, 98, m.cursor.index)
+	require.Empty(t, m.pendingKeys)
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
-	"sort"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/key"
@@ -22,29 +24,56 @@ import (
 
 // Keys
 type keyMap struct {
-	Up            key.Binding
-	Down          key.Binding
-	Left          key.Binding
-	Right         key.Binding
-	Top           key.Binding
-	Bottom        key.Binding
-	AllTasksTab   key.Binding
-	CompletedTab  key.Binding
-	TodayTab      key.Binding
-	InboxTab      key.Binding
-	Info          key.Binding
-	Done          key.Binding
-	Filter        key.Binding
-	SetInput      key.Binding
-	ClearInput    key.Binding
-	ExitInput     key.Binding
-	New           key.Binding
-	NewWithEditor key.Binding
-	CreateNewTask key.Binding
-	Edit          key.Binding
-	Sync          key.Binding
-	Help          key.Binding
-	Quit          key.Binding
+	Up             key.Binding
+	Down           key.Binding
+	Left           key.Binding
+	Right          key.Binding
+	Top            key.Binding
+	Bottom         key.Binding
+	AllTasksTab    key.Binding
+	CompletedTab   key.Binding
+	TodayTab       key.Binding
+	InboxTab       key.Binding
+	UpcomingTab    key.Binding
+	NextDay        key.Binding
+	PrevDay        key.Binding
+	FocusNextDay   key.Binding
+	FocusPrevDay   key.Binding
+	Reschedule     key.Binding
+	EmptyDays      key.Binding
+	CalendarTab    key.Binding
+	NextMonth      key.Binding
+	PrevMonth      key.Binding
+	GoToToday      key.Binding
+	Select         key.Binding
+	BoardTab       key.Binding
+	MoveCardLeft   key.Binding
+	MoveCardRight  key.Binding
+	NextProject    key.Binding
+	PrevProject    key.Binding
+	BoardMode      key.Binding
+	Info           key.Binding
+	Done           key.Binding
+	Filter         key.Binding
+	SetInput       key.Binding
+	ClearInput     key.Binding
+	ExitInput      key.Binding
+	New            key.Binding
+	NewWithEditor  key.Binding
+	CreateNewTask  key.Binding
+	Edit           key.Binding
+	Sync           key.Binding
+	Help           key.Binding
+	Quit           key.Binding
+	SwitchProfile  key.Binding
+	CommandPalette key.Binding
+	Complete       key.Binding
+	NextSuggestion key.Binding
+	PrevSuggestion key.Binding
+	PageUp         key.Binding
+	PageDown       key.Binding
+	HalfPageUp     key.Binding
+	HalfPageDown   key.Binding
 }
 
 type InputFieldCommand = string
@@ -57,9 +86,12 @@ const (
 	todayTab
 	allTasksTab
 	completedTab
+	upcomingTab
+	calendarTab
+	boardTab
 
 	// NOTE: make sure to increment counter if we add a new page
-	totalTab = 4
+	totalTab = 7
 )
 
 var (
@@ -117,6 +149,78 @@ var (
 			key.WithKeys("4"),
 			key.WithHelp("4", "completed tab"),
 		),
+		UpcomingTab: key.NewBinding(
+			key.WithKeys("5"),
+			key.WithHelp("5", "upcoming tab"),
+		),
+		NextDay: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "next day"),
+		),
+		PrevDay: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "previous day"),
+		),
+		FocusNextDay: key.NewBinding(
+			key.WithKeys("}"),
+			key.WithHelp("}", "focus next day"),
+		),
+		FocusPrevDay: key.NewBinding(
+			key.WithKeys("{"),
+			key.WithHelp("{", "focus previous day"),
+		),
+		Reschedule: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "move to focused day"),
+		),
+		EmptyDays: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", "toggle empty days"),
+		),
+		CalendarTab: key.NewBinding(
+			key.WithKeys("6"),
+			key.WithHelp("6", "calendar tab"),
+		),
+		NextMonth: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "next month"),
+		),
+		PrevMonth: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "previous month"),
+		),
+		GoToToday: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "today"),
+		),
+		Select: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "select"),
+		),
+		BoardTab: key.NewBinding(
+			key.WithKeys("7"),
+			key.WithHelp("7", "board tab"),
+		),
+		MoveCardLeft: key.NewBinding(
+			key.WithKeys("<"),
+			key.WithHelp("<", "move to previous column"),
+		),
+		MoveCardRight: key.NewBinding(
+			key.WithKeys(">"),
+			key.WithHelp(">", "move to next column"),
+		),
+		NextProject: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "next project"),
+		),
+		PrevProject: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "previous project"),
+		),
+		BoardMode: key.NewBinding(
+			key.WithKeys("b"),
+			key.WithHelp("b", "sections/labels"),
+		),
 		New: key.NewBinding(
 			key.WithKeys("n"),
 			key.WithHelp("n", "new"),
@@ -160,40 +264,43 @@ var (
 		Quit: key.NewBinding(
 			key.WithKeys("q", "ctrl+c"),
 			key.WithHelp("q", "quit"),
+		),
+		SwitchProfile: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "switch profile"),
+		),
+		CommandPalette: key.NewBinding(
+			key.WithKeys(":"),
+			key.WithHelp(":", "command palette"),
+		),
+		Complete: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "complete"),
+		),
+		NextSuggestion: key.NewBinding(
+			key.WithKeys("down", "ctrl+n"),
+			key.WithHelp("↓/ctrl+n", "next suggestion"),
+		),
+		PrevSuggestion: key.NewBinding(
+			key.WithKeys("up", "ctrl+p"),
+			key.WithHelp("↑/ctrl+p", "previous suggestion"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup", "ctrl+b"),
+			key.WithHelp("pgup/ctrl+b", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown", "ctrl+f"),
+			key.WithHelp("pgdown/ctrl+f", "page down"),
+		),
+		HalfPageUp: key.NewBinding(
+			key.WithKeys("ctrl+u"),
+			key.WithHelp("ctrl+u", "half page up"),
+		),
+		HalfPageDown: key.NewBinding(
+			key.WithKeys("ctrl+d"),
+			key.WithHelp("ctrl+d", "half page down"),
 		)}
-
-	defaultTextStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("15"))
-
-	cursorBackgroundStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("8"))
-
-	dimTextStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("8"))
-
-	chosenTextStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("3"))
-
-	projectStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("14"))
-
-	dueDateStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("5"))
-
-	dueDateOverdueStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("1"))
-
-	labelsStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("5"))
-
-	p1Style = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("1"))
-
-	p2Style = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("3"))
-
-	p3Style = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("4"))
 )
 
 type cursorPosition struct {
@@ -210,26 +317,54 @@ type inputField struct {
 type editorFinishedMsg struct{ err error }
 
 type model struct {
-	storage        Storage
-	keys           keyMap
-	totalWidth     int
-	totalHeight    int
-	listHeight     int
-	debug          bool
-	syncing        bool
-	todos          []Todo
-	filteredTodos  []Todo
-	todayTodos     []Todo
-	inboxTodos     []Todo
-	completedTodos []Todo
-	cursor         cursorPosition
-	tab            Tab
-	currentFilter  string
-	showHelp       bool
-	showInfo       bool
-	textInput      textinput.Model
-	inputField     inputField
-	syncError      error
+	storage             Storage
+	keys                keyMap
+	totalWidth          int
+	totalHeight         int
+	listHeight          int
+	debug               bool
+	syncing             bool
+	syncingInBackground bool
+	editing             bool // In the editor, background syncs wait for it
+	todos               []Todo
+	filteredTodos       []Todo
+	todayTodos          []Todo
+	inboxTodos          []Todo
+	completedTodos      []Todo
+	upcomingTodos       []Todo
+	upcomingDays        int
+	showEmptyDays       bool
+	focusedDay          int
+	calendar            calendar
+	calendarTodos       []Todo
+	sections            []Section
+	board               board
+	boardLabels         []string
+	cursor              cursorPosition
+	offset              int
+	infoOffset          int
+	lastClick           lastClick
+	paletteSelected     int
+	pendingKeys         []string
+	tab                 Tab
+	sortBy              string
+	editor              string
+	syncInterval        time.Duration
+	syncGen             int // Ticks from earlier schedules are dropped
+	syncFailures        int
+	lastSync            time.Time
+	setup               bool
+	tokenPath           string
+	config              Config
+	profile             string
+	currentFilter       string
+	showHelp            bool
+	showInfo            bool
+	textInput           textinput.Model
+	inputField          inputField
+	syncError           error
+	// Shown like the errors, until the palette is opened again
+	notice string
 }
 
 func NewModel(storage Storage, debug bool) model {
@@ -239,13 +374,18 @@ func NewModel(storage Storage, debug bool) model {
 	ti.Placeholder = ""
 	ti.Prompt = ""
 	return model{
-		storage:   storage,
-		keys:      keys,
-		debug:     debug,
-		tab:       todayTab,
-		showInfo:  true,
-		textInput: ti,
-		syncing:   true, // always try to sync on startup
+		storage:      storage,
+		keys:         keys,
+		debug:        debug,
+		tab:          todayTab,
+		sortBy:       sortDue,
+		showInfo:     true,
+		textInput:    ti,
+		upcomingDays: 7,
+		calendar: calendar{
+			day: startOfDay(dateFormatter.now()),
+		},
+		syncing: true, // always try to sync on startup
 		cursor: cursorPosition{
 			index: 0,
 		},
@@ -253,7 +393,65 @@ func NewModel(storage Storage, debug bool) model {
 }
 
 func (m model) Init() tea.Cmd {
-	return m.getLocalTodos
+	if m.setup {
+		// The minute ticks keep the "last synced" of the top bar up to date after the setup
+		return tea.Batch(textinput.Blink, minuteTick())
+	}
+	return tea.Batch(m.getLocalTodos, minuteTick(), syncTick(m.syncGen, m.syncInterval))
+}
+
+type SyncTick struct {
+	gen int
+}
+
+// The result of a background sync. Its failures are counted for the backoff, unlike the
+// SyncErrors of the actions of the user.
+type BackgroundSync struct {
+	data []Todo
+	err  error
+}
+
+// Longest wait between background syncs after failed syncs
+const maxSyncBackoff = time.Hour
+
+// Schedules the next background sync after the sync interval, if enabled.
+// Called when a sync is done, so the interval counts from the last sync.
+func (m *model) scheduleSync() tea.Cmd {
+	if m.syncInterval <= 0 {
+		return nil
+	}
+	m.syncGen++
+	return syncTick(m.syncGen, syncBackoff(m.syncInterval, m.syncFailures))
+}
+
+func syncTick(gen int, d time.Duration) tea.Cmd {
+	if d <= 0 {
+		return nil
+	}
+	return tea.Tick(d, func(time.Time) tea.Msg {
+		return SyncTick{gen: gen}
+	})
+}
+
+// The interval, doubled for each failed sync in a row, up to an hour
+func syncBackoff(interval time.Duration, failures int) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < maxSyncBackoff; i++ {
+		d *= 2
+	}
+	if d > maxSyncBackoff && interval < maxSyncBackoff {
+		d = maxSyncBackoff
+	}
+	return d
+}
+
+type MinuteTick struct{}
+
+// Redraws every minute, to keep "last synced" up to date
+func minuteTick() tea.Cmd {
+	return tea.Tick(time.Minute, func(time.Time) tea.Msg {
+		return MinuteTick{}
+	})
 }
 
 // Async functions
@@ -272,83 +470,69 @@ func (m model) getLocalTodos() tea.Msg {
 }
 
 func (m model) fetchTodos() tea.Msg {
-	todos, err := m.storage.fetchTodos()
-	if err != nil {
-		return SyncError{ // TODO: new error
-			err: err,
-		}
-	}
-	return FetchedTodos{
-		data: todos,
+	return syncResult(m.storage.fetchTodos())
+}
+
+// The synced tasks, or the SyncError. Rejected commands do not fail the sync, they are shown with its tasks.
+func syncResult(todos []Todo, err error) tea.Msg {
+	if err != nil && !isRejected(err) {
+		return SyncError{err: err}
 	}
+	return FetchedTodos{data: todos, rejected: err}
+}
+
+func (m model) backgroundSync() tea.Msg {
+	todos, err := m.storage.fetchTodos()
+	return BackgroundSync{data: todos, err: err}
 }
 
 func (m model) quickAdd(content string) func() tea.Msg {
 	return func() tea.Msg {
-		todos, err := m.storage.quickAdd(content)
-		if err != nil {
-			return SyncError{ // TODO: new error
-				err: err,
-			}
-		}
-		return FetchedTodos{
-			data: todos,
-		}
+		_, todos, err := m.storage.quickAdd(content)
+		return syncResult(todos, err)
 	}
 }
 
 func (m model) markAsDone(todo Todo) func() tea.Msg {
 	return func() tea.Msg {
-		todos, err := m.storage.markAsDone(todo)
-		if err != nil {
-			return SyncError{ // TODO: new error
-				err: err,
-			}
-		}
-		return FetchedTodos{
-			data: todos,
-		}
+		return syncResult(m.storage.markAsDone(todo))
 	}
 }
 
 func (m model) newTask(todo Todo) func() tea.Msg {
 	return func() tea.Msg {
-		todos, err := m.storage.newTask(todo)
-		if err != nil {
-			return SyncError{ // TODO: new error
-				err: err,
-			}
-		}
-		return FetchedTodos{
-			data: todos,
-		}
+		return syncResult(m.storage.newTask(todo))
 	}
 }
 
 func (m model) editTask(data EditTaskData) func() tea.Msg {
 	return func() tea.Msg {
-		todos, err := m.storage.editTask(data)
-		if err != nil {
-			return SyncError{ // TODO: new error
-				err: err,
-			}
-		}
-		return FetchedTodos{
-			data: todos,
-		}
+		return syncResult(m.storage.editTask(data))
 	}
 }
 
-func newTaskInEditor(m model) tea.Cmd {
-	editor := os.Getenv("EDITOR")
+func (m model) editorCommand(path string) *exec.Cmd {
+	return editorCommand(m.editor, path)
+}
+
+// The editor setting, then $EDITOR
+func editorCommand(editor, path string) *exec.Cmd {
 	if editor == "" {
-		editor = "vim"
+		editor = os.Getenv("EDITOR")
 	}
+	if editor == "" {
+		editor = "vim" // Always! 💪
+	}
+	args := strings.Fields(editor)
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+func newTaskInEditor(m model) tea.Cmd {
 	path, err := createNewTaskFile()
 	if err != nil {
 		return nil
 	}
-	c := exec.Command(editor, path)
+	c := m.editorCommand(path)
 	return tea.ExecProcess(c, func(err error) tea.Msg {
 		if err != nil {
 			return editorFinishedMsg{err}
@@ -365,7 +549,6 @@ func newTaskInEditor(m model) tea.Cmd {
 
 // TODO:
 // handle error if cursor is out of scope
-// handle multiple pages
 func (m model) getCurrentTodo() (Todo, error) {
 	var todos []Todo
 	switch m.tab {
@@ -375,6 +558,12 @@ func (m model) getCurrentTodo() (Todo, error) {
 		todos = m.inboxTodos
 	case completedTab:
 		todos = m.completedTodos
+	case upcomingTab:
+		todos = m.upcomingTodos
+	case calendarTab:
+		todos = m.calendarTodos
+	case boardTab:
+		todos = m.boardTodos()
 	default:
 		todos = m.filteredTodos
 	}
@@ -386,12 +575,7 @@ func (m model) getCurrentTodo() (Todo, error) {
 	return todo, nil
 }
 
-func editTaskInEditor(todo Todo, path string) tea.Cmd {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		editor = "vim" // Always! 💪
-	}
-	c := exec.Command(editor, path)
+func editTaskInEditor(c *exec.Cmd, todo Todo, path string) tea.Cmd {
 	return tea.ExecProcess(c, func(err error) tea.Msg {
 		if err != nil {
 			return editorFinishedMsg{err}
@@ -409,42 +593,124 @@ func editTaskInEditor(todo Todo, path string) tea.Cmd {
 	})
 }
 
+func (m model) editCurrentTodo() (model, tea.Cmd) {
+	todo, err := m.getCurrentTodo()
+	if err != nil {
+		// handle error
+	}
+	path, err := createEditFile(todo)
+	if err != nil {
+		// TODO: handle error
+	}
+	m.syncing = true
+	m.editing = true
+	return m, editTaskInEditor(m.editorCommand(path), todo, path)
+}
+
 /////////////
 // Update
 ////////////
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	cursor := m.cursor
+	res, cmd := m.update(msg)
+	if m, ok := res.(model); ok {
+		m.keepCursorVisible()
+		if m.cursor != cursor {
+			m.infoOffset = 0
+		}
+		return m, cmd
+	}
+	return res, cmd
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
+	case Exported:
+		if msg.err != nil {
+			m.syncError = fmt.Errorf("export: %w", msg.err)
+			return m, nil
+		}
+		m.notice = fmt.Sprintf("exported %d tasks to %s", msg.tasks, msg.path)
+		return m, nil
+
 	case SyncError:
+		m.syncError = msg.err
+		m.syncing = false
+		return m, nil
+
+	case editorFinishedMsg:
+		m.editing = false
+		m.syncing = false
 		m.syncError = msg.err
 		return m, nil
 
 	case NewTask:
+		m.editing = false
 		return m, m.newTask(msg.data)
 
 	case EditTask:
+		m.editing = false
 		return m, m.editTask(msg.data)
 
 	case LocalTodos:
 		m.todos = msg.data
 		filtered := filterContents(msg.data, m.currentFilter)
-		sort.Sort(ByDueThenPriority(filtered))
+		sortTodos(filtered, m.sortBy)
 		m.filteredTodos = filtered
-		m.todayTodos = filterToday(m.filteredTodos)
-		m.inboxTodos = filterInbox(m.filteredTodos)
+		m.filterLists()
 		return m, m.fetchTodos
 
 	case FetchedTodos:
-		m.todos = msg.data
-		filtered := filterContents(msg.data, m.currentFilter)
-		sort.Sort(ByDueThenPriority(filtered))
-		m.filteredTodos = filtered
-		m.todayTodos = filterToday(m.filteredTodos)
-		m.inboxTodos = filterInbox(m.filteredTodos)
 		m.syncing = false
+		cmd := m.setFetchedTodos(msg.data)
+		if msg.rejected != nil {
+			m.syncError = msg.rejected
+		}
+		return m, cmd
+
+	case BackgroundSync:
+		m.syncingInBackground = false
+		if msg.err != nil && !isRejected(msg.err) {
+			m.syncError = msg.err
+			m.syncFailures++
+			return m, m.scheduleSync()
+		}
+		cmd := m.setFetchedTodos(msg.data)
+		if msg.err != nil {
+			m.syncError = msg.err
+		}
+		return m, cmd
+
+	case Sections:
+		m.sections = msg.data
+		return m, nil
+
+	case Labels:
+		setLabelColors(msg.data)
 		return m, nil
 
+	case ProfileSwitched:
+		return m.useProfile(msg)
+
+	case SyncTick:
+		if msg.gen != m.syncGen {
+			return m, nil
+		}
+		if m.syncingInBackground {
+			return m, nil // The next one is scheduled when it is done
+		}
+		// Dont replace the tasks under an open input field or editor, try again next interval
+		if m.syncing || m.editing || m.inputField.enabled || m.setup {
+			return m, m.scheduleSync()
+		}
+		m.syncingInBackground = true
+		return m, m.backgroundSync
+
+	case MinuteTick:
+		return m, minuteTick()
+
 	// Set window size
 	case tea.WindowSizeMsg:
 		m.totalHeight = msg.Height
@@ -453,18 +719,24 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	if m.setup {
+		return m.updateSetup(msg)
+	}
+
+	if m.inputField.enabled && m.inputField.command == inputFieldCommandPalette {
+		return m.updatePalette(msg)
+	}
+
 	if m.inputField.enabled {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch msg.String() {
-
-			case "enter":
+			switch {
+			case key.Matches(msg, m.keys.SetInput):
 				value := m.textInput.Value()
 				if m.inputField.command == inputFieldCommandFilter {
 					m.currentFilter = value
 					m.filteredTodos = filterContents(m.todos, value)
-					m.todayTodos = filterToday(m.filteredTodos)
-					m.inboxTodos = filterInbox(m.filteredTodos)
+					m.filterLists()
 				}
 				m.textInput.SetValue("")
 				m.textInput.Prompt = ""
@@ -477,7 +749,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.inputField.command = ""
 				return m, nil
-			case "ctrl+c", "esc": // TODO: use keys instead (keymatches)
+			case key.Matches(msg, m.keys.ClearInput, m.keys.ExitInput):
 				m.textInput.SetValue("")
 				m.textInput.Prompt = ""
 				m.inputField.enabled = false
@@ -485,8 +757,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Delete current filter
 				m.filteredTodos = m.todos
 				m.currentFilter = ""
-				m.todayTodos = filterToday(m.filteredTodos)
-				m.inboxTodos = filterInbox(m.filteredTodos)
+				m.filterLists()
 				m.moveCursor(tea.KeyMsg{})
 				return m, nil
 			}
@@ -497,8 +768,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		if m.inputField.command == inputFieldCommandFilter {
 			m.filteredTodos = filterContents(m.todos, m.textInput.Value())
-			m.todayTodos = filterToday(m.filteredTodos)
-			m.inboxTodos = filterInbox(m.filteredTodos)
+			m.filterLists()
 		}
 
 		return m, cmd
@@ -506,26 +776,47 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Normal list view
 	switch msg := msg.(type) {
+	case tea.MouseMsg:
+		return m.updateMouse(msg)
 	case tea.KeyMsg:
-		switch {
-		case key.Matches(msg, m.keys.Edit):
-			todo, err := m.getCurrentTodo()
-			if err != nil {
-				// handle error
+		msg, ok := m.keySequence(msg)
+		if !ok {
+			return m, nil
+		}
+		if m.tab == calendarTab {
+			if m, cmd, handled := m.updateCalendar(msg); handled {
+				return m, cmd
 			}
-			path, err := createEditFile(todo)
-			if err != nil {
-				// TODO: handle error
+		}
+		if m.tab == boardTab {
+			if m, cmd, handled := m.updateBoard(msg); handled {
+				return m, cmd
 			}
-			m.syncing = true
-			return m, editTaskInEditor(todo, path)
+		}
+		switch {
+		case key.Matches(msg, m.keys.Edit):
+			return m.editCurrentTodo()
 		case key.Matches(msg, m.keys.NewWithEditor):
+			m.editing = true
 			return m, newTaskInEditor(m)
-		case key.Matches(msg, m.keys.Down, m.keys.Up, m.keys.Bottom, m.keys.Top):
+		case key.Matches(msg, m.keys.Down, m.keys.Up, m.keys.Bottom, m.keys.Top, m.keys.PageUp, m.keys.PageDown, m.keys.HalfPageUp, m.keys.HalfPageDown):
 			m.moveCursor(msg)
-		case key.Matches(msg, m.keys.AllTasksTab), key.Matches(msg, m.keys.CompletedTab), key.Matches(msg, m.keys.TodayTab), key.Matches(msg, m.keys.InboxTab):
+			if m.tab == upcomingTab {
+				m.focusCursorDay()
+			}
+		case key.Matches(msg, m.keys.tabKeys()...):
 			m.changeTab(msg)
-			m.refreshCursor()
+		case m.tab == upcomingTab && key.Matches(msg, m.keys.NextDay, m.keys.PrevDay, m.keys.FocusNextDay, m.keys.FocusPrevDay):
+			delta := 1
+			if key.Matches(msg, m.keys.PrevDay, m.keys.FocusPrevDay) {
+				delta = -1
+			}
+			m.moveFocusedDay(delta, key.Matches(msg, m.keys.NextDay, m.keys.PrevDay))
+		case m.tab == upcomingTab && key.Matches(msg, m.keys.Reschedule):
+			return m.rescheduleToFocusedDay()
+		case m.tab == upcomingTab && key.Matches(msg, m.keys.EmptyDays):
+			m.showEmptyDays = !m.showEmptyDays
+			m.focusCursorDay()
 		case key.Matches(msg, m.keys.Done):
 			todo, err := m.getCurrentTodo()
 			if err != nil {
@@ -556,6 +847,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.inputField.enabled = true
 			m.inputField.command = inputFieldCommandNew
 			return m, nil
+		case key.Matches(msg, m.keys.SwitchProfile):
+			if len(m.config.profileNames()) < 2 {
+				return m, nil
+			}
+			if m.syncing || m.syncingInBackground {
+				m.syncError = fmt.Errorf("can not switch profile while syncing")
+				return m, nil
+			}
+			return m, m.switchProfile(m.nextProfile())
+		case key.Matches(msg, m.keys.CommandPalette):
+			m.openPalette()
+			return m, nil
 		case key.Matches(msg, m.keys.Sync):
 			m.syncing = true
 			m.syncError = nil
@@ -574,9 +877,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 ////////////
 
 func (m model) View() string {
+	if m.setup {
+		return m.renderSetup()
+	}
 	top := m.topBar()
 	mainList := m.getMainList()
-	content := m.renderViewList(mainList)
+	var content string
+	if m.tab == upcomingTab {
+		content = m.renderAgenda(mainList)
+	} else if m.tab == calendarTab {
+		content = m.renderCalendar(mainList)
+	} else if m.tab == boardTab {
+		content = m.renderBoard()
+	} else {
+		content = m.renderViewList(mainList)
+	}
 	return m.debugView() + top + content + m.getEmptyLines(content+top) + m.bottomBar()
 }
 
@@ -602,6 +917,12 @@ func (m model) getMainList() []Todo {
 		return m.inboxTodos
 	case completedTab:
 		return m.completedTodos
+	case upcomingTab:
+		return m.upcomingTodos
+	case calendarTab:
+		return m.calendarTodos
+	case boardTab:
+		return m.boardTodos()
 	default:
 		return []Todo{}
 	}
@@ -631,6 +952,12 @@ func (m model) tabToString(p Tab) string {
 			style = p3Style
 		}
 		return "Today tasks" + style.Render(extra)
+	case upcomingTab:
+		return "Upcoming"
+	case calendarTab:
+		return "Calendar"
+	case boardTab:
+		return "Board"
 	}
 	return ""
 }
@@ -639,8 +966,37 @@ func (m model) topBar() string {
 	pageStyle := lipgloss.NewStyle().
 		Width(m.totalWidth)
 
+	// The tabs keep a line of their own, cut instead of wrapped, so the clicks on them can be mapped back
+	tabs := lipgloss.NewStyle().
+		MaxWidth(m.totalWidth).
+		Render(m.tabLine())
+
 	var s string
-	s += "  "
+	if m.currentFilter != "" {
+		s += chosenTextStyle.Render("  filter: on")
+	} else {
+		s += dimTextStyle.Render("  filter: off")
+	}
+	if len(m.config.profileNames()) > 1 {
+		s += "  " + projectStyle.Render("["+m.profile+"]")
+	}
+	if m.syncing || m.syncingInBackground {
+		s += "  " + dimTextStyle.Render("syncing...")
+	} else if !m.lastSync.IsZero() {
+		s += "  " + dimTextStyle.Render(lastSynced(m.lastSync, dateFormatter.now()))
+	}
+
+	statusStyle := lipgloss.NewStyle().
+		Width(m.totalWidth * 2 / 3).
+		Align(lipgloss.Left)
+	s = lipgloss.NewStyle().MaxWidth(m.totalWidth * 2 / 3).Render(s)
+
+	content := tabs + "\n" + statusStyle.Render(s) + m.showError()
+	return pageStyle.Render(content) + "\n" + "\n"
+}
+
+func (m model) tabLine() string {
+	s := "  "
 	for i := 0; i < totalTab; i++ {
 		if m.tab == i {
 			s += chosenTextStyle.Render(m.tabToString(i))
@@ -652,37 +1008,31 @@ func (m model) topBar() string {
 			s += " | "
 		}
 	}
+	return s
+}
 
-	if m.syncing {
-		s += "  " + dimTextStyle.Render("syncing...")
-	}
-
-	s += "\n"
-	if m.currentFilter != "" {
-		s += chosenTextStyle.Render("  filter: on")
-	} else {
-		s += dimTextStyle.Render("  filter: off")
+func lastSynced(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "last synced just now"
+	case d < time.Hour:
+		return fmt.Sprintf("last synced %d min ago", int(d.Minutes()))
 	}
-
-	tabStyle := lipgloss.NewStyle().
-		Height(2).
-		Width(m.totalWidth / 2).
-		Align(lipgloss.Left)
-
-	content := tabStyle.Render(s) + m.showError()
-	return pageStyle.Render(content) + "\n" + "\n"
+	return fmt.Sprintf("last synced %d h ago", int(d.Hours()))
 }
 
 // TODO: create a notifcation popup ish thing.
 func (m model) showError() string {
-	errorStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("1")).
+	errorStyle := errorTextStyle.Copy().
 		Width(m.totalWidth / 3).
 		Height(1).
 		Align(lipgloss.Right)
 	var e string
 	if m.syncError != nil {
 		e += strings.TrimSpace(fmt.Sprintf("%s", m.syncError))
+	} else if m.notice != "" {
+		return errorStyle.Copy().Foreground(dimTextStyle.GetForeground()).Render(m.notice)
 	}
 	return errorStyle.Render(e)
 }
@@ -690,11 +1040,9 @@ func (m model) showError() string {
 func (m model) bottomBar() string {
 	var input string
 	if m.inputField.enabled {
-		inputStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("3")).
+		inputStyle := inputTextStyle.Copy().
 			Align(lipgloss.Left)
-		filterStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("15")).
+		filterStyle := defaultTextStyle.Copy().
 			Align(lipgloss.Left)
 		if m.inputField.command == inputFieldCommandFilter {
 			input = filterStyle.Render(m.textInput.View())
@@ -704,12 +1052,17 @@ func (m model) bottomBar() string {
 	}
 	w, _ := lipgloss.Size(input)
 
-	style := lipgloss.NewStyle().
+	style := helpTextStyle.Copy().
 		Width(m.totalWidth - w). // m.totalWidth might be 0
-		Foreground(lipgloss.Color("12")).
 		Align(lipgloss.Right)
 
 	var s string
+	if m.inputField.enabled && m.inputField.command == inputFieldCommandPalette {
+		return input + style.Render(m.renderPaletteSuggestions())
+	}
+	if len(m.pendingKeys) > 0 {
+		s += chosenTextStyle.Render(strings.Join(m.pendingKeys, " ")+" …") + "   "
+	}
 	ks := m.getValidKeys(m.keys)
 	for i, v := range ks {
 		h := v.Help()
@@ -738,25 +1091,28 @@ func (m model) renderInfo(todo Todo, height int) string {
 	}
 	t := table.New().
 		Border(lipgloss.NormalBorder()).
-		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("8"))).
+		BorderStyle(borderStyle).
 		BorderRow(true).
 		Height(height).
 		Width(m.totalWidth / 2).
 		Rows(rows...)
-	return t.Render()
+	return scrollLines(t.Render(), m.infoOffset)
 }
 
 func (m model) renderViewList(todos []Todo) string {
 	projectLength := projectNameSize(todos, 30)
 	content := ""
-	showing := 0
 	info := ""
 	width := m.totalWidth
 	if m.showInfo {
 		width = (m.totalWidth / 2)
 	}
-	listHeight := m.listHeight - 1
-	for i, v := range todos {
+	end := m.offset + m.viewHeight()
+	if end > len(todos) {
+		end = len(todos)
+	}
+	for i := m.offset; i < end; i++ {
+		v := todos[i]
 		if m.cursor.index == i {
 			content += "→ " + v.renderInList(width, projectLength)
 			if m.showInfo {
@@ -766,12 +1122,8 @@ func (m model) renderViewList(todos []Todo) string {
 			content += "  " + v.renderInList(width, projectLength)
 		}
 		content += "\n"
-		showing++
-		if i >= listHeight {
-			break
-		}
 	}
-	content += fmt.Sprintf("\nshowing %d of %d", showing, len(todos))
+	content += "\n" + m.scrollIndicator(len(todos))
 	if m.showInfo {
 		content = lipgloss.NewStyle().
 			Width(m.totalWidth / 2).
@@ -791,7 +1143,7 @@ func (t Todo) renderInList(w int, projectNameLength int) string {
 	desc := defaultTextStyle.Render(withSize(t.Content, w-50))
 	labels := ""
 	for _, l := range t.Labels {
-		labels += labelsStyle.Render(" @" + l)
+		labels += labelStyle(l).Render(" @" + l)
 	}
 	project := t.ProjectDisplay(projectNameLength)
 	due := t.DueDisplay(false)
@@ -817,15 +1169,32 @@ func (t Todo) renderInList(w int, projectNameLength int) string {
 // Utils
 ////////////
 
-// Returns the valid command keys to be used, based on current state
+// Returns the valid command keys to be used, based on current state.
+// The help is generated from the bindings of the current view.
 func (m model) getValidKeys(k keyMap) []key.Binding {
-	if m.inputField.enabled {
-		return []key.Binding{k.SetInput, k.ClearInput, k.ExitInput}
+	var bindings []key.Binding
+	switch {
+	case m.inputField.enabled:
+		bindings = k.context(keyContextNamed("input"))
+	case !m.showHelp:
+		bindings = []key.Binding{k.Help, k.Quit}
+	default:
+		c := m.keyContext()
+		if c.list {
+			// Only the keys of the tab, the list keys are shown in the other tabs
+			c.list = false
+			bindings = append(k.context(c), k.Help, k.Quit)
+		} else {
+			bindings = k.context(c)
+		}
 	}
-	if m.showHelp {
-		return []key.Binding{k.Sync, k.New, k.NewWithEditor, k.Edit, k.Done, k.Filter, k.Up, k.Down, k.Top, k.Bottom, k.AllTasksTab, k.CompletedTab, k.Help, k.Quit}
+	enabled := make([]key.Binding, 0, len(bindings))
+	for _, b := range bindings {
+		if b.Enabled() {
+			enabled = append(enabled, b)
+		}
 	}
-	return []key.Binding{k.Help, k.Quit}
+	return enabled
 }
 
 func (m model) getEmptyLines(content string) string {
@@ -843,16 +1212,31 @@ func (m model) getEmptyLines(content string) string {
 }
 
 func (m *model) changeTab(km tea.KeyMsg) {
-	switch {
-	case key.Matches(km, m.keys.InboxTab):
-		m.tab = 0
-	case key.Matches(km, m.keys.TodayTab):
-		m.tab = 1
-	case key.Matches(km, m.keys.AllTasksTab):
-		m.tab = 2
-	case key.Matches(km, m.keys.CompletedTab):
-		m.tab = 3
+	for tab, binding := range m.keys.tabKeys() {
+		if key.Matches(km, binding) {
+			m.setTab(tab)
+		}
+	}
+}
+
+func (m *model) setTab(tab Tab) {
+	if todo, err := m.getCurrentTodo(); err == nil && tab == boardTab {
+		m.setBoardProject(todo.ProjectId)
 	}
+	m.offset = 0
+	m.tab = tab
+	m.refreshCursor()
+	if m.tab == upcomingTab {
+		m.focusCursorDay()
+	}
+}
+
+// Updates the lists for each tab, based on the filtered todos
+func (m *model) filterLists() {
+	m.todayTodos = filterToday(m.filteredTodos)
+	m.inboxTodos = filterInbox(m.filteredTodos)
+	m.upcomingTodos = filterUpcoming(m.filteredTodos, dateFormatter.now(), m.upcomingDays)
+	m.calendarTodos = filterDay(m.filteredTodos, m.calendar.day)
 }
 
 func (m *model) refreshCursor() {
@@ -865,6 +1249,40 @@ func (m *model) refreshCursor() {
 	}
 }
 
+// Shows the synced tasks, keeping the cursor on its task, and schedules the next background sync
+func (m *model) setFetchedTodos(todos []Todo) tea.Cmd {
+	current, err := m.getCurrentTodo()
+	m.todos = todos
+	filter := m.currentFilter
+	if m.inputField.enabled && m.inputField.command == inputFieldCommandFilter {
+		filter = m.textInput.Value() // Keep the preview of the filter being typed
+	}
+	filtered := filterContents(todos, filter)
+	sortTodos(filtered, m.sortBy)
+	m.filteredTodos = filtered
+	m.filterLists()
+	if err == nil {
+		m.keepCursorOn(current.Id)
+	}
+	m.lastSync = dateFormatter.now()
+	if m.syncFailures > 0 {
+		m.syncFailures = 0
+		m.syncError = nil
+	}
+	return tea.Batch(m.getLocalSections, m.getLocalLabels, m.scheduleSync())
+}
+
+// Moves the cursor to the task with the id, if it is still in the list
+func (m *model) keepCursorOn(id string) {
+	for i, t := range m.getMainList() {
+		if t.Id == id {
+			m.cursor.index = i
+			return
+		}
+	}
+	m.refreshCursor()
+}
+
 func (m *model) moveCursor(km tea.KeyMsg) {
 	maxIndex := len(m.getMainList()) - 1
 	if maxIndex <= 0 {
@@ -889,6 +1307,14 @@ func (m *model) moveCursor(km tea.KeyMsg) {
 		if !(m.cursor.index >= maxIndex) {
 			m.cursor.index++
 		}
+	case key.Matches(km, m.keys.PageUp):
+		m.scrollBy(-m.viewHeight())
+	case key.Matches(km, m.keys.PageDown):
+		m.scrollBy(m.viewHeight())
+	case key.Matches(km, m.keys.HalfPageUp):
+		m.scrollBy(-m.viewHeight() / 2)
+	case key.Matches(km, m.keys.HalfPageDown):
+		m.scrollBy(m.viewHeight() / 2)
 	}
 }
 
@@ -898,55 +1324,155 @@ func (m *model) moveCursor(km tea.KeyMsg) {
 
 func main() {
 
-	// TODO: only open "tui" app when no args are given
-	// TODO: create a sync command for completed tasks
-
-	var tokenPath string
-	homeDir, err := os.UserHomeDir()
+	dir, err := configDir()
 	if err != nil {
 		fmt.Print(err)
 		os.Exit(1)
 	}
-	defaultTokenPath := homeDir + "/.todoist.token"
-	flag.StringVar(&tokenPath, "t", defaultTokenPath, "Path to todoist API token.")
-
-	var dbPath string
-	flag.StringVar(&dbPath, "d", homeDir+"/.cache/todui.db", "Path to local db.")
+	configPath := flag.String("c", "", "Path to config file. (default $TODUI_CONFIG or "+filepath.Join(dir, "config.toml")+")")
+
+	// Flags that overrides a setting in the config file
+	settingFlags := map[string]string{
+		"profile":       "profile",
+		"t":             "token_path",
+		"d":             "db_path",
+		"tab":           "default_tab",
+		"sort":          "sort",
+		"info":          "show_info",
+		"editor":        "editor",
+		"date-format":   "date_format",
+		"sync-interval": "sync_interval",
+		"upcoming-days": "upcoming_days",
+		"empty-days":    "empty_days",
+		"board-labels":  "board_labels",
+	}
+	defaults := defaultConfig()
+	flag.String("profile", defaultProfile, "Profile to use.")
+	flag.String("t", "", "Path to todoist API token. (default "+filepath.Join(dir, "token")+" or ~/.todoist.token)")
+	flag.String("d", "", "Path to local db. (default $XDG_DATA_HOME/todui/todui.db or ~/.cache/todui.db)")
+	flag.String("tab", defaults.DefaultTab, "Tab shown on startup: "+strings.Join(tabNames, ", "))
+	flag.String("sort", defaults.Sort, "Sort tasks by due, priority or project.")
+	flag.Bool("info", defaults.ShowInfo, "Show the task info pane.")
+	flag.String("editor", "", "Editor command. (default $EDITOR)")
+	flag.String("date-format", defaults.DateFormat, "Go time layout used for dates that are not shown as relative.")
+	flag.String("sync-interval", defaults.SyncInterval, "Sync periodically in the background, e.g. 5m. Failed syncs wait longer, up to an hour. 0 disables it.")
+	flag.Int("upcoming-days", defaults.UpcomingDays, "Number of days shown in the upcoming tab.")
+	flag.Bool("empty-days", defaults.EmptyDays, "Show days without tasks in the upcoming tab.")
+	flag.String("board-labels", "", "Comma separated labels to use as columns in the board tab.")
 
 	debug := flag.Bool("debug", false, "Run tui in debug mode")
 
-	sync := flag.Bool("sync", false, "do a full sync to local db")
+	sync := flag.Bool("sync", false, "Fetch everything again and rebuild the local db, including completed tasks.")
 
+	flag.Usage = func() {
+		cliUsage(flag.CommandLine.Output())
+		fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	if flag.NArg() > 0 && !isCLICommand(flag.Arg(0)) {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", flag.Arg(0))
+		cliUsage(os.Stderr)
+		os.Exit(exitUsage)
+	}
 
-	db, err := NewDB(dbPath)
-	if err != nil {
-		fmt.Print(err)
-		os.Exit(1)
+	// Errors before a command runs go to stderr like its own errors, instead of into its output
+	exitWithError := func(err error) {
+		if flag.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Println(err)
+		}
+		os.Exit(exitError)
 	}
-	defer db.Close()
 
-	api, err := NewAPI(tokenPath)
+	config, err := setupConfig(*configPath, dir, settingFlags)
 	if err != nil {
-		fmt.Print(err)
-		os.Exit(1)
+		exitWithError(err)
+	}
+	keyMap, err := keys.remap(config.Leader, config.Keys)
+	if err != nil {
+		exitWithError(err)
+	}
+	err = setupTheme(config)
+	if err != nil {
+		exitWithError(err)
+	}
+	tab, err := parseTab(config.DefaultTab)
+	if err != nil {
+		exitWithError(err)
+	}
+	sortBy, err := parseSort(config.Sort)
+	if err != nil {
+		exitWithError(err)
+	}
+	syncInterval, err := config.syncInterval()
+	if err != nil {
+		exitWithError(err)
+	}
+	dateFormatter.absoluteFormat = config.DateFormat
+	if cache, err := cacheDir(); err == nil && os.MkdirAll(cache, 0755) == nil {
+		editDir = cache
 	}
 
-	storage := Storage{
-		api: api,
-		db:  db,
+	profile, err := config.getProfile(config.Profile)
+	if err != nil {
+		exitWithError(err)
+	}
+	storage, err := openStorage(profile)
+	if err != nil && (!errors.Is(err, errNoToken) || !isTerminal() || *sync || flag.NArg() > 0) {
+		exitWithError(err)
+	}
+	noToken := err != nil
+
+	if flag.NArg() > 0 {
+		code := runCLI(cli{
+			storage: storage,
+			config:  config,
+			stdout:  os.Stdout,
+			stderr:  os.Stderr,
+		}, flag.Args())
+		storage.db.Close()
+		os.Exit(code)
 	}
 
-	if *sync == true {
-		// run sync command
-		fmt.Println("TODO")
+	if *sync {
+		report, err := storage.fullSync()
+		storage.db.Close()
+		if isRejected(err) {
+			fmt.Println(err)
+		} else if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printSyncReport(os.Stdout, report)
 		return
 	}
 
-	model := NewModel(storage, *debug)
+	initial := NewModel(storage, *debug)
+	initial.keys = keyMap
+	initial.tab = tab
+	initial.sortBy = sortBy
+	initial.showInfo = config.ShowInfo
+	initial.editor = config.Editor
+	initial.syncInterval = syncInterval
+	initial.upcomingDays = config.UpcomingDays
+	initial.showEmptyDays = config.EmptyDays
+	initial.boardLabels = config.BoardLabels
+	initial.config = config
+	initial.profile = config.Profile
+	initial.tokenPath = profile.TokenPath
+	if noToken {
+		initial.startSetup()
+	}
 
-	p := tea.NewProgram(model)
-	if _, err := p.Run(); err != nil {
+	p := tea.NewProgram(initial, tea.WithMouseCellMotion())
+	res, err := p.Run()
+	if m, ok := res.(model); ok {
+		// The profile might have been switched
+		m.storage.db.Close()
+	}
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
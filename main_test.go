@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+func update(m model, msg tea.Msg) model {
+	res, _ := m.Update(msg)
+	return res.(model)
+}
+
+func TestSyncBackoff(t *testing.T) {
+	require.Equal(t, 5*time.Minute, syncBackoff(5*time.Minute, 0))
+	require.Equal(t, 10*time.Minute, syncBackoff(5*time.Minute, 1))
+	require.Equal(t, 40*time.Minute, syncBackoff(5*time.Minute, 3))
+	require.Equal(t, time.Hour, syncBackoff(5*time.Minute, 20))
+	require.Equal(t, 2*time.Hour, syncBackoff(2*time.Hour, 3))
+}
+
+func TestLastSynced(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, "last synced just now", lastSynced(now.Add(-30*time.Second), now))
+	require.Equal(t, "last synced 5 min ago", lastSynced(now.Add(-5*time.Minute), now))
+	require.Equal(t, "last synced 2 h ago", lastSynced(now.Add(-150*time.Minute), now))
+}
+
+func TestBackgroundSync(t *testing.T) {
+	m := newScrollModel(3)
+	m.syncInterval = 5 * time.Minute
+	m = update(m, FetchedTodos{data: m.todos})
+	require.False(t, m.syncing)
+	require.Contains(t, m.topBar(), "last synced just now")
+	gen := m.syncGen
+	require.NotZero(t, gen)
+
+	// A tick from before the last sync is dropped
+	m = update(m, SyncTick{gen: gen - 1})
+	require.False(t, m.syncing)
+
+	// An open input field is left alone
+	m = press(m, "/")
+	m = typeText(m, "task")
+	m = update(m, SyncTick{gen: m.syncGen})
+	require.False(t, m.syncing)
+	require.Equal(t, "task", m.textInput.Value())
+	require.Greater(t, m.syncGen, gen)
+	m, _ = sendKey(m, tea.KeyEsc)
+
+	m = update(m, SyncTick{gen: m.syncGen})
+	require.True(t, m.syncingInBackground)
+	require.Contains(t, m.topBar(), "syncing...")
+	// A failed action of the user does not end the background sync, or count for the backoff
+	m = update(m, SyncError{err: errors.New("could not move the task")})
+	require.True(t, m.syncingInBackground)
+	require.Zero(t, m.syncFailures)
+	gen = m.syncGen
+	m = update(m, SyncTick{gen: gen})
+	require.Equal(t, gen, m.syncGen)
+
+	m = update(m, BackgroundSync{err: errors.New("offline")})
+	require.False(t, m.syncingInBackground)
+	require.Equal(t, 1, m.syncFailures)
+	require.Greater(t, m.syncGen, gen)
+
+	// The cursor stays on the same task when the list changes
+	m.cursor.index = 2
+	current, err := m.getCurrentTodo()
+	require.NoError(t, err)
+	m = update(m, FetchedTodos{data: m.todos[1:]})
+	todo, err := m.getCurrentTodo()
+	require.NoError(t, err)
+	require.Equal(t, current.Id, todo.Id)
+	require.Zero(t, m.syncFailures)
+	require.NoError(t, m.syncError)
+
+	// Rejected commands are shown, but the sync went through
+	rejected := rejectedError{errs: []error{errors.New("item_close: Item not found")}}
+	m = update(m, SyncTick{gen: m.syncGen})
+	m = update(m, BackgroundSync{data: m.todos, err: rejected})
+	require.False(t, m.syncingInBackground)
+	require.Zero(t, m.syncFailures)
+	require.Equal(t, rejected, m.syncError)
+	require.Equal(t, FetchedTodos{data: m.todos, rejected: rejected}, syncResult(m.todos, rejected))
+	require.Equal(t, SyncError{err: errors.New("offline")}, syncResult(nil, errors.New("offline")))
+}
+
+func TestBackgroundSyncUnderFilter(t *testing.T) {
+	m := newScrollModel(10)
+	m.syncingInBackground = true
+	m = press(m, "/")
+	m = typeText(m, "3")
+	require.Equal(t, 1, len(m.filteredTodos))
+
+	// The sync ends while the filter is typed, the preview is kept
+	m = update(m, BackgroundSync{data: m.todos})
+	require.Equal(t, 1, len(m.filteredTodos))
+	require.Equal(t, "task number 3", m.filteredTodos[0].Content)
+	require.Equal(t, "3", m.textInput.Value())
+}
+
+func TestInitSetup(t *testing.T) {
+	m := NewModel(Storage{}, false)
+	m.startSetup()
+	// The minute ticks start with the setup screen, so they run after it
+	batch, ok := m.Init()().(tea.BatchMsg)
+	require.True(t, ok)
+	require.Equal(t, 2, len(batch))
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -23,8 +24,11 @@ var markdown = goldmark.New(
 	),
 )
 
+// Directory of the files opened in the editor
+var editDir = os.TempDir()
+
 func createNewTaskFile() (string, error) {
-	path := fmt.Sprintf("/tmp/%d.md", time.Now().Unix())
+	path := filepath.Join(editDir, fmt.Sprintf("%d.md", time.Now().Unix()))
 	var b bytes.Buffer
 	fmt.Fprintf(&b, "---")
 	fmt.Fprintf(&b, "labels: ")
@@ -185,7 +189,7 @@ func parseUpdatedChildren(children []Todo, node *ast.List, source []byte) ([]Tod
 }
 
 func createEditFile(todo Todo) (string, error) {
-	path := fmt.Sprintf("/tmp/%s.md", todo.Id)
+	path := filepath.Join(editDir, todo.Id+".md")
 	var b bytes.Buffer
 	fmt.Fprintf(&b, "---\n")
 	fmt.Fprintf(&b, "due: %s\n", todo.Due.Date)
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Whole projects as markdown, for reading and for turning notes into tasks:
+//
+//	# Work
+//
+//	- [ ] Write report due:2024-01-05 p1 @office
+//	  - [ ] Draft
+//	- [ ] Weekly review due:"every friday"
+//
+//	## Q1
+//
+//	- [ ] Plan the quarter
+//
+// Level one headings are projects and the headings below them are sections.
+// Every list item is a task, nested lists are subtasks, and paragraphs in an item are its description.
+// due:, p1 to p4 and @label in an item are taken out of the content. A due that is not a date is sent
+// as a due string, like due:tomorrow.
+
+var (
+	markdownDueRegex      = regexp.MustCompile(`(?:^|\s)due:(?:"([^"]*)"|(\S+))`)
+	markdownPriorityRegex = regexp.MustCompile(`(?:^|\s)(p[1-4])(?:\s|$)`)
+	markdownLabelRegex    = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+)
+
+func exportMarkdown(w io.Writer, data exportData) error {
+	bw := bufio.NewWriter(w)
+	projects := make([]string, 0)
+	for _, t := range data.todos {
+		if !Contains(projects, t.ProjectId) {
+			projects = append(projects, t.ProjectId)
+		}
+	}
+	for _, t := range data.completed {
+		if !Contains(projects, t.ProjectId) {
+			projects = append(projects, t.ProjectId)
+		}
+	}
+	for i, project := range projects {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		name := data.projectName(project)
+		if name == "" {
+			name = "Inbox"
+		}
+		fmt.Fprintf(bw, "# %s\n\n", name)
+		for _, t := range data.todos {
+			if t.ProjectId == project && t.SectionId == "" {
+				writeMarkdownTask(bw, t, "")
+			}
+		}
+		for _, t := range data.completed {
+			if t.ProjectId == project {
+				writeMarkdownTask(bw, t.Todo, "")
+			}
+		}
+		for _, s := range data.sections {
+			if s.ProjectId != project {
+				continue
+			}
+			header := false
+			for _, t := range data.todos {
+				if t.SectionId != s.Id {
+					continue
+				}
+				if !header {
+					fmt.Fprintf(bw, "\n## %s\n\n", s.Name)
+					header = true
+				}
+				writeMarkdownTask(bw, t, "")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func writeMarkdownTask(w io.Writer, t Todo, indent string) {
+	check := " "
+	if t.Checked {
+		check = "x"
+	}
+	fmt.Fprintf(w, "%s- [%s] %s%s\n", indent, check, strings.Join(strings.Fields(t.Content), " "), markdownMeta(t))
+	if description := strings.TrimSpace(t.Description); description != "" {
+		fmt.Fprintln(w)
+		for _, line := range strings.Split(description, "\n") {
+			fmt.Fprintf(w, "%s  %s\n", indent, line)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, c := range t.Children {
+		writeMarkdownTask(w, c, indent+"  ")
+	}
+}
+
+func markdownMeta(t Todo) string {
+	s := ""
+	if t.Due.IsRecurring && t.Due.String != "" {
+		s += fmt.Sprintf(" due:%q", t.Due.String)
+	} else if t.Due.Date != "" {
+		s += " due:" + t.Due.Date
+	}
+	if t.Priority > 1 {
+		s += " " + renderPriority(t.Priority)
+	}
+	for _, l := range t.Labels {
+		s += " @" + l
+	}
+	return s
+}
+
+func parseMarkdownProject(r io.Reader, data exportData) ([]importTask, error) {
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	document := markdown.Parser().Parse(text.NewReader(source))
+	tasks := make([]importTask, 0)
+	project, section := "", ""
+	for node := document.FirstChild(); node != nil; node = node.NextSibling() {
+		switch n := node.(type) {
+		case *ast.Heading:
+			name := strings.TrimSpace(string(n.Text(source)))
+			if n.Level == 1 {
+				project, section = name, ""
+			} else {
+				section = name
+			}
+		case *ast.List:
+			for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+				t, ok := markdownTask(item, source)
+				if !ok {
+					continue
+				}
+				t.project, t.section = project, section
+				tasks = append(tasks, t)
+			}
+		}
+	}
+	return tasks, nil
+}
+
+// The task of a list item, false for an empty item
+func markdownTask(item ast.Node, source []byte) (importTask, bool) {
+	t := importTask{priority: 1}
+	description := make([]string, 0)
+	for node := item.FirstChild(); node != nil; node = node.NextSibling() {
+		if list, ok := node.(*ast.List); ok {
+			for c := list.FirstChild(); c != nil; c = c.NextSibling() {
+				if child, ok := markdownTask(c, source); ok {
+					t.children = append(t.children, child)
+				}
+			}
+			continue
+		}
+		block := blockText(node, source)
+		if node != item.FirstChild() {
+			description = append(description, block)
+			continue
+		}
+		t.ref = fmt.Sprintf("line %d", lineNumber(node, source))
+		t.parseTitle(block)
+	}
+	t.description = strings.Join(description, "\n\n")
+	return t, t.content != "" || len(t.children) > 0
+}
+
+func (t *importTask) parseTitle(title string) {
+	title = strings.Join(strings.Fields(title), " ")
+	for _, prefix := range []string{"[ ] ", "[x] ", "[X] "} {
+		if strings.HasPrefix(title, prefix) {
+			t.checked = prefix != "[ ] "
+			title = strings.TrimPrefix(title, prefix)
+		}
+	}
+	if m := markdownDueRegex.FindStringSubmatch(title); m != nil {
+		due := m[1] + m[2]
+		_, dateErr := time.Parse(dueDateLayout, due)
+		_, timeErr := time.Parse(dueFloatingLayout, due)
+		if dateErr == nil || timeErr == nil {
+			t.due.Date = due
+		} else {
+			t.due.String = due
+		}
+		title = markdownDueRegex.ReplaceAllString(title, "")
+	}
+	if m := markdownPriorityRegex.FindStringSubmatch(title); m != nil {
+		t.priority = parsePriority(m[1])
+		title = markdownPriorityRegex.ReplaceAllString(title, " ")
+	}
+	for _, m := range markdownLabelRegex.FindAllStringSubmatch(title, -1) {
+		t.labels = append(t.labels, m[1])
+	}
+	title = markdownLabelRegex.ReplaceAllString(title, "")
+	t.content = strings.Join(strings.Fields(title), " ")
+}
+
+// The source of a block, keeping the inline markdown
+func blockText(node ast.Node, source []byte) string {
+	lines := node.Lines()
+	if lines.Len() == 0 {
+		return strings.TrimSpace(string(node.Text(source)))
+	}
+	s := make([]string, lines.Len())
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		s[i] = strings.TrimRight(string(line.Value(source)), "\n")
+	}
+	return strings.TrimSpace(strings.Join(s, "\n"))
+}
+
+func lineNumber(node ast.Node, source []byte) int {
+	if node.Lines().Len() == 0 {
+		return 0
+	}
+	return bytes.Count(source[:node.Lines().At(0).Start], []byte("\n")) + 1
+}
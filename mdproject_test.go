@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportMarkdown(t *testing.T) {
+	data := exportData{
+		projects: []Project{{Id: "11", Name: "Work"}},
+		sections: []Section{{Id: "21", ProjectId: "11", Name: "Q1"}, {Id: "22", ProjectId: "11", Name: "Empty"}},
+		todos: []Todo{
+			{Id: "1", ProjectId: "11", Content: "Write report", Description: "for monday\nin [docs](http://docs)", Priority: 4, Labels: []string{"office"}, Due: Due{Date: "2024-01-05"},
+				Children: []Todo{{Id: "2", ProjectId: "11", Content: "Draft"}}},
+			{Id: "3", ProjectId: "11", SectionId: "21", Content: "Weekly review", Due: Due{Date: "2024-01-05", String: "every friday", IsRecurring: true}},
+		},
+	}
+	var b bytes.Buffer
+	require.NoError(t, exportMarkdown(&b, data))
+	require.Equal(t, `# Work
+
+- [ ] Write report due:2024-01-05 p1 @office
+
+  for monday
+  in [docs](http://docs)
+
+  - [ ] Draft
+
+## Q1
+
+- [ ] Weekly review due:"every friday"
+`, b.String())
+
+	tasks, err := parseMarkdownProject(&b, data)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tasks))
+	require.Equal(t, "Write report", tasks[0].content)
+	require.Equal(t, "for monday\nin [docs](http://docs)", tasks[0].description)
+	require.Equal(t, "Work", tasks[0].project)
+	require.Equal(t, "2024-01-05", tasks[0].due.Date)
+	require.Equal(t, 4, tasks[0].priority)
+	require.Equal(t, []string{"office"}, tasks[0].labels)
+	require.Equal(t, "Draft", tasks[0].children[0].content)
+	require.Equal(t, "Q1", tasks[1].section)
+	require.Equal(t, "every friday", tasks[1].due.String)
+}
+
+func TestParseMeetingNotes(t *testing.T) {
+	tasks, err := parseMarkdownProject(strings.NewReader(`# Planning meeting
+
+Some notes that are not tasks.
+
+## Actions
+
+1. Send the **minutes** due:tomorrow @mail
+2. [x] Book a room
+   * Ask facilities p2
+3.
+
+- Follow up with Alex
+`), exportData{})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(tasks))
+	require.Equal(t, "Send the **minutes**", tasks[0].content)
+	require.Equal(t, "tomorrow", tasks[0].due.String)
+	require.Equal(t, []string{"mail"}, tasks[0].labels)
+	require.Equal(t, "Planning meeting", tasks[0].project)
+	require.Equal(t, "Actions", tasks[0].section)
+	require.Equal(t, "line 7", tasks[0].ref)
+	require.True(t, tasks[1].checked)
+	require.Equal(t, "Ask facilities", tasks[1].children[0].content)
+	require.Equal(t, 3, tasks[1].children[0].priority)
+	require.Equal(t, "Follow up with Alex", tasks[2].content)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Mouse support. Positions are mapped back to rows by mirroring the layout in View:
+// the debug line, two lines of tabs and filter, a blank line and then the list.
+
+const (
+	doubleClickTime = 400 * time.Millisecond
+	wheelRows       = 3
+)
+
+type lastClick struct {
+	at  time.Time
+	row int
+}
+
+// First line of the list
+func (m model) listTop() int {
+	top := 3
+	if m.debug {
+		top++
+	}
+	return top
+}
+
+// The tab at x on the tab line, or -1
+func (m model) tabAt(x int) Tab {
+	pos := 2
+	for i := 0; i < totalTab; i++ {
+		w := lipgloss.Width(m.tabToString(i))
+		if x >= pos && x < pos+w {
+			return i
+		}
+		pos += w + len(" | ")
+	}
+	return -1
+}
+
+// Drops the first n lines of s
+func scrollLines(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	if n >= len(lines) {
+		n = len(lines) - 1
+	}
+	return strings.Join(lines[n:], "\n")
+}
+
+func (m model) updateMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	overInfo := m.showInfo && msg.X >= m.totalWidth/2 && m.tab != boardTab
+	switch msg.Type {
+	case tea.MouseWheelUp:
+		if overInfo {
+			if m.infoOffset > 0 {
+				m.infoOffset--
+			}
+			return m, nil
+		}
+		m.scrollBy(-wheelRows)
+		m.syncAgendaFocus()
+		return m, nil
+	case tea.MouseWheelDown:
+		if overInfo {
+			m.infoOffset++
+			return m, nil
+		}
+		m.scrollBy(wheelRows)
+		m.syncAgendaFocus()
+		return m, nil
+	case tea.MouseLeft:
+		return m.click(msg)
+	}
+	return m, nil
+}
+
+func (m *model) syncAgendaFocus() {
+	if m.tab == upcomingTab {
+		m.focusCursorDay()
+	}
+}
+
+func (m model) click(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	top := m.listTop()
+	if msg.Y == top-3 {
+		if tab := m.tabAt(msg.X); tab >= 0 {
+			m.setTab(tab)
+		}
+		return m, nil
+	}
+	if msg.Y < top || (m.showInfo && msg.X >= m.totalWidth/2 && m.tab != boardTab) {
+		return m, nil
+	}
+
+	y := msg.Y - top
+	index := -1
+	switch m.tab {
+	case boardTab:
+		index = m.boardClick(msg.X, y)
+	case calendarTab:
+		index = m.calendarClick(msg.X, y)
+	case upcomingTab:
+		index = m.agendaClick(y)
+	default:
+		if y < m.viewHeight() && m.offset+y < len(m.getMainList()) {
+			index = m.offset + y
+		}
+	}
+	if index < 0 {
+		return m, nil
+	}
+	m.cursor.index = index
+
+	// Double click opens the task in the editor
+	doubleClick := m.lastClick.row == index && time.Since(m.lastClick.at) < doubleClickTime
+	m.lastClick = lastClick{at: time.Now(), row: index}
+	if doubleClick {
+		m.lastClick = lastClick{}
+		return m.editCurrentTodo()
+	}
+	return m, nil
+}
+
+// Returns the task index at row y of the agenda, and focuses the day of the row
+func (m *model) agendaClick(y int) int {
+	row := m.offset + y
+	if y >= m.viewHeight() {
+		return -1
+	}
+	index := 0
+	current := 0
+	for i, day := range m.agenda() {
+		if current == row {
+			// Clicked a day header
+			m.focusedDay = i
+			return -1
+		}
+		current++
+		for range day.todos {
+			if current == row {
+				m.focusedDay = i
+				return index
+			}
+			current++
+			index++
+		}
+	}
+	return -1
+}
+
+// Selects the clicked day in the grid, or returns the clicked task below it
+func (m *model) calendarClick(x, y int) int {
+	grid := calendarHeight(m.calendar.day)
+	if y >= grid {
+		y -= grid
+		if y < m.viewHeight() && m.offset+y < len(m.calendarTodos) {
+			m.calendar.focusList = true
+			return m.offset + y
+		}
+		return -1
+	}
+	week := y - 2 // month and weekday lines
+	weekday := (x - 2) / calendarCellWidth
+	if week < 0 || week >= calendarWeeks(m.calendar.day) || x < 2 || weekday > 6 {
+		return -1
+	}
+	m.calendar.day = calendarStart(m.calendar.day).AddDate(0, 0, week*7+weekday)
+	m.calendar.focusList = false
+	m.moveCalendarDay(0, 0)
+	return -1
+}
+
+// Focuses the clicked column, and returns the clicked card
+func (m *model) boardClick(x, y int) int {
+	columns := m.boardColumns()
+	l := m.boardLayout(len(columns))
+	if l.visible == 0 {
+		return -1
+	}
+	column := l.first + x/(m.totalWidth/l.visible)
+	if column >= l.first+l.visible {
+		return -1
+	}
+	offset := 0
+	if column == m.board.column {
+		offset = l.offset
+	}
+	m.board.column = column
+	card := y - 3 // header, border and column title
+	if card < 0 || card >= l.height || offset+card >= len(columns[column].todos) {
+		m.refreshCursor()
+		return -1
+	}
+	return offset + card
+}
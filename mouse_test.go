@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/require"
+)
+
+func click(m model, x, y int) (model, tea.Cmd) {
+	res, cmd := m.Update(tea.MouseMsg{X: x, Y: y, Type: tea.MouseLeft})
+	return res.(model), cmd
+}
+
+func TestMouseClickRow(t *testing.T) {
+	m := newScrollModel(100)
+	m, _ = click(m, 10, m.listTop()+4)
+	require.Equal(t, 4, m.cursor.index)
+
+	// Below the list
+	m, _ = click(m, 10, m.listTop()+m.viewHeight()+1)
+	require.Equal(t, 4, m.cursor.index)
+}
+
+func TestMouseDoubleClick(t *testing.T) {
+	m := newScrollModel(100)
+	m, cmd := click(m, 10, m.listTop()+2)
+	require.Nil(t, cmd)
+	m, cmd = click(m, 10, m.listTop()+2)
+	require.NotNil(t, cmd)
+	require.True(t, m.syncing)
+}
+
+func TestMouseClickTab(t *testing.T) {
+	m := newScrollModel(10)
+	// "  Inbox | Today tasks ..."
+	m, _ = click(m, 3, m.listTop()-3)
+	require.Equal(t, inboxTab, m.tab)
+	x := 2 + len("Inbox | ") + 1
+	m, _ = click(m, x, m.listTop()-3)
+	require.Equal(t, todayTab, m.tab)
+}
+
+func TestMouseClickTabAfterStatus(t *testing.T) {
+	m := newScrollModel(100)
+	m.lastSync = time.Now()
+	for i := range m.filteredTodos {
+		m.filteredTodos[i].ProjectId = "11"
+	}
+	// The tabs with the sync status are wider than the tab area, but stay on one line
+	lines := strings.Split(m.topBar(), "\n")
+	require.Equal(t, 4, len(lines))
+	require.Contains(t, lines[0], "| Board")
+	require.Contains(t, lines[1], "last synced just now")
+
+	m, _ = click(m, strings.Index(lines[0], "Board")+1, m.listTop()-3)
+	require.Equal(t, boardTab, m.tab)
+	// The board shows the project of the task under the cursor
+	require.Equal(t, "11", m.board.projectId)
+
+	// Cut, instead of wrapped, when the window is too narrow
+	res, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: 40})
+	m = res.(model)
+	lines = strings.Split(m.topBar(), "\n")
+	require.Equal(t, 4, len(lines))
+	require.Equal(t, 40, lipgloss.Width(lines[0]))
+}
+
+func TestMouseWheel(t *testing.T) {
+	m := newScrollModel(100)
+	res, _ := m.Update(tea.MouseMsg{X: 10, Y: 10, Type: tea.MouseWheelDown})
+	m = res.(model)
+	require.Equal(t, wheelRows, m.cursor.index)
+
+	res, _ = m.Update(tea.MouseMsg{X: 10, Y: 10, Type: tea.MouseWheelUp})
+	m = res.(model)
+	require.Equal(t, 0, m.cursor.index)
+
+	// Scrolling the info pane does not move the cursor
+	m.showInfo = true
+	res, _ = m.Update(tea.MouseMsg{X: m.totalWidth - 1, Y: 10, Type: tea.MouseWheelDown})
+	m = res.(model)
+	require.Equal(t, 0, m.cursor.index)
+	require.Equal(t, 1, m.infoOffset)
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Org-mode, for editing tasks in Emacs and applying the edits back:
+//
+//	* TODO [#A] Write report :office:
+//	  DEADLINE: <2024-01-05 Fri +1w>
+//	  :PROPERTIES:
+//	  :TODOIST_ID: 1
+//	  :PROJECT:  Work
+//	  :END:
+//	  for monday
+//	** TODO Draft
+//
+// p1 to p3 are [#A] to [#C], labels are tags and subtasks are subtrees. The due date is the
+// DEADLINE, a SCHEDULED date is read too. Headlines with a TODOIST_ID update that task when
+// imported, other TODO and DONE headlines are created. An unchanged date keeps the recurrence of a task,
+// and a later date with the same repeater, like after marking it done in Emacs, completes the occurrence.
+// Completed tasks are exported with a TODOIST_COMPLETED id, and are not imported again.
+
+var (
+	orgHeadlineRegex  = regexp.MustCompile(`^(\*+)\s+(.*)$`)
+	orgTagsRegex      = regexp.MustCompile(`\s+(:[\w@#%:]+:)\s*$`)
+	orgPriorityRegex  = regexp.MustCompile(`^\[#([A-Z])\]\s*`)
+	orgPropertyRegex  = regexp.MustCompile(`^:([\w-]+):\s*(.*)$`)
+	orgTimestampRegex = regexp.MustCompile(`(DEADLINE|SCHEDULED):\s*<(\d{4}-\d{2}-\d{2})(?:\s+[^\s\d>]+)?(?:\s+(\d{1,2}:\d{2}))?(?:\s+(\.?\+\d+[dwmy]))?[^>]*>`)
+	orgTagCharsRegex  = regexp.MustCompile(`[^\w@#%]`)
+)
+
+func exportOrg(w io.Writer, data exportData) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range data.todos {
+		writeOrgTask(bw, t, 1, data)
+	}
+	for _, t := range data.completed {
+		fmt.Fprintf(bw, "* DONE %s\n", orgTitle(t.Todo))
+		if c, err := time.Parse(time.RFC3339, t.CompletedAt); err == nil {
+			fmt.Fprintf(bw, "  CLOSED: [%s]\n", c.Local().Format("2006-01-02 Mon 15:04"))
+		}
+		fmt.Fprintf(bw, "  :PROPERTIES:\n  :TODOIST_COMPLETED: %s\n", t.Id)
+		if t.ProjectName != "" {
+			fmt.Fprintf(bw, "  :PROJECT:  %s\n", t.ProjectName)
+		}
+		fmt.Fprintf(bw, "  :END:\n")
+	}
+	return bw.Flush()
+}
+
+func writeOrgTask(w io.Writer, t Todo, level int, data exportData) {
+	keyword := "TODO"
+	if t.Checked {
+		keyword = "DONE"
+	}
+	fmt.Fprintf(w, "%s %s %s\n", strings.Repeat("*", level), keyword, orgTitle(t))
+	indent := strings.Repeat(" ", level+1)
+	if timestamp, ok := orgTimestamp(t.Due); ok {
+		fmt.Fprintf(w, "%sDEADLINE: %s\n", indent, timestamp)
+	}
+	fmt.Fprintf(w, "%s:PROPERTIES:\n", indent)
+	fmt.Fprintf(w, "%s:TODOIST_ID: %s\n", indent, t.Id)
+	if level == 1 {
+		fmt.Fprintf(w, "%s:PROJECT:  %s\n", indent, t.ProjectName)
+		if section := data.sectionName(t.SectionId); section != "" {
+			fmt.Fprintf(w, "%s:SECTION:  %s\n", indent, section)
+		}
+	}
+	fmt.Fprintf(w, "%s:END:\n", indent)
+	if description := strings.TrimSpace(t.Description); description != "" {
+		for _, line := range strings.Split(description, "\n") {
+			if strings.HasPrefix(line, "*") {
+				line = "," + line // Not a headline
+			}
+			fmt.Fprintf(w, "%s%s\n", indent, line)
+		}
+	}
+	for _, c := range t.Children {
+		writeOrgTask(w, c, level+1, data)
+	}
+}
+
+func orgTitle(t Todo) string {
+	s := ""
+	if p := todoTxtPriority(t.Priority); p != "" {
+		s += "[#" + p + "] "
+	}
+	s += strings.Join(strings.Fields(t.Content), " ")
+	if len(t.Labels) > 0 {
+		tags := make([]string, len(t.Labels))
+		for i, l := range t.Labels {
+			tags[i] = orgTag(l)
+		}
+		s += " :" + strings.Join(tags, ":") + ":"
+	}
+	return s
+}
+
+func orgTag(label string) string {
+	return orgTagCharsRegex.ReplaceAllString(label, "_")
+}
+
+func orgTimestamp(due Due) (string, bool) {
+	t, hasTime, err := due.Time()
+	if err != nil {
+		return "", false
+	}
+	s := "<" + t.Format("2006-01-02 Mon")
+	if hasTime {
+		s += " " + t.Format("15:04")
+	}
+	if repeater, ok := orgRepeater(due); ok {
+		s += " " + repeater
+	}
+	return s + ">", true
+}
+
+// A repeater like +1w. Only a single day in each period can be repeated, since the date is the first one.
+func orgRepeater(due Due) (string, bool) {
+	if !due.IsRecurring {
+		return "", false
+	}
+	r, err := parseRecurrence(due.String)
+	if err != nil || len(r.weekdays) > 1 || len(r.monthDays) > 1 {
+		return "", false
+	}
+	s := "+"
+	if r.fromCompletion {
+		s = ".+"
+	}
+	return s + fmt.Sprint(r.interval) + string("dwmy"[r.unit]), true
+}
+
+func parseOrg(r io.Reader, data exportData) ([]importTask, error) {
+	existing := make(map[string]Todo)
+	var walk func(todos []Todo)
+	walk = func(todos []Todo) {
+		for _, t := range todos {
+			existing[t.Id] = t
+			walk(t.Children)
+		}
+	}
+	walk(data.todos)
+	labels := make(map[string]string)
+	for _, t := range existing {
+		for _, l := range t.Labels {
+			labels[orgTag(l)] = l
+		}
+	}
+
+	type entry struct {
+		level int
+		task  *importTask
+	}
+	tasks := make([]importTask, 0)
+	var stack []entry
+	// The task the lines belong to, nil outside of tasks
+	var current *importTask
+	currentLevel := 0
+	inProperties := false
+	inDrawer := false
+	skip := false
+
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+		if m := orgHeadlineRegex.FindStringSubmatch(line); m != nil {
+			level := len(m[1])
+			for len(stack) > 0 && stack[len(stack)-1].level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			current, inProperties, inDrawer, skip = nil, false, false, false
+			keyword, rest, _ := strings.Cut(m[2], " ")
+			if keyword != "TODO" && keyword != "DONE" {
+				continue
+			}
+			t := importTask{ref: fmt.Sprintf("line %d", n), checked: keyword == "DONE", priority: 1}
+			if tags := orgTagsRegex.FindStringSubmatch(rest); tags != nil {
+				for _, tag := range strings.Split(strings.Trim(tags[1], ":"), ":") {
+					if l, ok := labels[tag]; ok {
+						tag = l
+					}
+					t.labels = append(t.labels, tag)
+				}
+				rest = orgTagsRegex.ReplaceAllString(rest, "")
+			}
+			if p := orgPriorityRegex.FindStringSubmatch(rest); p != nil {
+				t.priority = t.parsePriority(p[1])
+				rest = orgPriorityRegex.ReplaceAllString(rest, "")
+			}
+			t.content = strings.TrimSpace(rest)
+			if len(stack) == 0 {
+				tasks = append(tasks, t)
+				current = &tasks[len(tasks)-1]
+			} else {
+				parent := stack[len(stack)-1].task
+				parent.children = append(parent.children, t)
+				current = &parent.children[len(parent.children)-1]
+			}
+			currentLevel = level
+			stack = append(stack, entry{level: level, task: current})
+			continue
+		}
+		if current == nil || skip {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == ":PROPERTIES:":
+			inProperties = true
+		case trimmed == ":END:":
+			inProperties, inDrawer = false, false
+		case inProperties:
+			if m := orgPropertyRegex.FindStringSubmatch(trimmed); m != nil {
+				switch strings.ToUpper(m[1]) {
+				case "TODOIST_ID":
+					if _, ok := existing[m[2]]; ok {
+						current.id = m[2]
+					} else {
+						current.notes = append(current.notes, "task "+m[2]+" not found, created as a new task")
+					}
+				case "TODOIST_COMPLETED":
+					current.content, skip = "", true
+				case "PROJECT":
+					current.project = m[2]
+				case "SECTION":
+					current.section = m[2]
+				}
+			}
+		case inDrawer:
+		case strings.HasPrefix(trimmed, ":") && strings.HasSuffix(trimmed, ":") && len(trimmed) > 2:
+			inDrawer = true // Like :LOGBOOK:
+		case orgTimestampRegex.MatchString(trimmed) || strings.HasPrefix(trimmed, "CLOSED:"):
+			for _, m := range orgTimestampRegex.FindAllStringSubmatch(trimmed, -1) {
+				if m[1] == "SCHEDULED" && current.due.Date != "" {
+					continue // The deadline wins
+				}
+				current.due = Due{Date: m[2]}
+				current.repeater = m[4]
+				if m[3] != "" {
+					t, _ := time.Parse("15:04", m[3])
+					current.due.Date += "T" + t.Format("15:04:05")
+				}
+			}
+		default:
+			line = strings.TrimPrefix(line, strings.Repeat(" ", currentLevel+1))
+			line = strings.TrimPrefix(line, ",")
+			current.description += line + "\n"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return orgFinish(tasks, existing), nil
+}
+
+// Drops completed tasks, trims the descriptions, and keeps the due of existing tasks
+func orgFinish(tasks []importTask, existing map[string]Todo) []importTask {
+	res := make([]importTask, 0, len(tasks))
+	for _, t := range tasks {
+		if t.content == "" {
+			continue
+		}
+		t.description = strings.TrimSpace(t.description)
+		if old, ok := existing[t.id]; ok && t.due.Date != "" {
+			t.keepOrgDue(old)
+		}
+		t.children = orgFinish(t.children, existing)
+		res = append(res, t)
+	}
+	return res
+}
+
+// An unchanged date keeps the due of the task as it is. A recurring task keeps its due string while the
+// repeater is the same, since a date alone would make it a one-off task: a later date, like Emacs gives
+// when an occurrence is marked done, closes the occurrence, and Todoist moves it to the next one.
+func (t *importTask) keepOrgDue(old Todo) {
+	date := orgDate(old.Due)
+	if date == t.due.Date {
+		t.due = old.Due
+		return
+	}
+	repeater, _ := orgRepeater(old.Due)
+	if !old.Due.IsRecurring || t.repeater == "" || t.repeater != repeater {
+		return
+	}
+	if t.due.Date > date {
+		t.checked = true
+		t.notes = append(t.notes, "the occurrence of "+date+" is completed")
+	} else {
+		t.notes = append(t.notes, "due "+t.due.Date+" not imported, the task recurs "+old.Due.String)
+	}
+	t.due = old.Due
+}
+
+// The local date of a due, as it is imported
+func orgDate(due Due) string {
+	t, hasTime, err := due.Time()
+	if err != nil {
+		return ""
+	}
+	if hasTime {
+		return t.Format(dueFloatingLayout)
+	}
+	return t.Format(dueDateLayout)
+}
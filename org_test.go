@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportOrg(t *testing.T) {
+	data := exportData{
+		sections: []Section{{Id: "21", ProjectId: "11", Name: "Q1"}},
+		todos: []Todo{
+			{Id: "1", ProjectId: "11", ProjectName: "Work", SectionId: "21", Content: "Write report", Description: "for monday\n* not a headline", Priority: 4, Labels: []string{"office", "deep-work"}, Due: Due{Date: "2024-01-05", String: "every friday", IsRecurring: true},
+				Children: []Todo{{Id: "2", ProjectId: "11", ProjectName: "Work", Content: "Draft", Due: Due{Date: "2024-01-04T09:30:00"}}}},
+		},
+		completed: []completedTask{{Todo: Todo{Id: "9", ProjectName: "Work", Content: "Old"}}},
+	}
+	var b bytes.Buffer
+	require.NoError(t, exportOrg(&b, data))
+	require.Equal(t, `* TODO [#A] Write report :office:deep_work:
+  DEADLINE: <2024-01-05 Fri +1w>
+  :PROPERTIES:
+  :TODOIST_ID: 1
+  :PROJECT:  Work
+  :SECTION:  Q1
+  :END:
+  for monday
+  ,* not a headline
+** TODO Draft
+   DEADLINE: <2024-01-04 Thu 09:30>
+   :PROPERTIES:
+   :TODOIST_ID: 2
+   :END:
+* DONE Old
+  :PROPERTIES:
+  :TODOIST_COMPLETED: 9
+  :PROJECT:  Work
+  :END:
+`, b.String())
+
+	// Unchanged, the tasks are updated as they are
+	tasks, err := parseOrg(strings.NewReader(b.String()), data)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(tasks))
+	require.Equal(t, "1", tasks[0].id)
+	require.Equal(t, "Write report", tasks[0].content)
+	require.Equal(t, "for monday\n* not a headline", tasks[0].description)
+	require.Equal(t, []string{"office", "deep-work"}, tasks[0].labels)
+	require.Equal(t, data.todos[0].Due, tasks[0].due)
+	require.Equal(t, "Q1", tasks[0].section)
+	require.Equal(t, "2", tasks[0].children[0].id)
+	require.Equal(t, data.todos[0].Children[0].Due, tasks[0].children[0].due)
+}
+
+func TestParseOrgEdits(t *testing.T) {
+	data := exportData{todos: []Todo{{Id: "1", ProjectName: "Work", Content: "Write report", Priority: 4, Due: Due{Date: "2024-01-05"}}}}
+	tasks, err := parseOrg(strings.NewReader(`#+TITLE: Tasks
+* DONE [#B] Write the report :office:
+  SCHEDULED: <2024-01-07 Sun> DEADLINE: <2024-01-08 Mon 9:00>
+  :PROPERTIES:
+  :TODOIST_ID: 1
+  :END:
+  :LOGBOOK:
+  CLOCK: [2024-01-02 Tue 10:00]--[2024-01-02 Tue 11:00] =>  1:00
+  :END:
+* Notes
+** TODO Call Alex
+   :PROPERTIES:
+   :TODOIST_ID: 99
+   :END:
+*** TODO Find the number
+`), data)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tasks))
+	require.Equal(t, "1", tasks[0].id)
+	require.True(t, tasks[0].checked)
+	require.Equal(t, 3, tasks[0].priority)
+	require.Equal(t, "Write the report", tasks[0].content)
+	require.Equal(t, Due{Date: "2024-01-08T09:00:00"}, tasks[0].due)
+	require.Equal(t, "", tasks[0].description)
+	require.Equal(t, "", tasks[1].id)
+	require.Equal(t, "line 11", tasks[1].ref)
+	require.Equal(t, []string{"task 99 not found, created as a new task"}, tasks[1].notes)
+	require.Equal(t, "Find the number", tasks[1].children[0].content)
+
+	plan, err := planImport(tasks, data)
+	require.NoError(t, err)
+	require.Equal(t, "item_update", plan.commands[0].Type)
+	require.Equal(t, "item_close", plan.commands[1].Type)
+}
+
+func TestParseOrgRecurring(t *testing.T) {
+	weekly := Due{Date: "2024-01-05", String: "every friday", IsRecurring: true}
+	data := exportData{todos: []Todo{
+		{Id: "1", Content: "Weekly review", Due: weekly},
+		{Id: "2", Content: "Water plants", Due: weekly},
+		{Id: "3", Content: "Backup", Due: weekly},
+		{Id: "4", Content: "Standup", Due: weekly},
+	}}
+	tasks, err := parseOrg(strings.NewReader(`* TODO Weekly review
+  DEADLINE: <2024-01-12 Fri +1w>
+  :PROPERTIES:
+  :TODOIST_ID: 1
+  :END:
+* TODO Water plants
+  DEADLINE: <2024-01-20 Sat>
+  :PROPERTIES:
+  :TODOIST_ID: 2
+  :END:
+* DONE Backup
+  DEADLINE: <2024-01-05 Fri +1w>
+  :PROPERTIES:
+  :TODOIST_ID: 3
+  :END:
+* TODO Standup
+  DEADLINE: <2024-01-03 Wed +1w>
+  :PROPERTIES:
+  :TODOIST_ID: 4
+  :END:
+`), data)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(tasks))
+
+	// Marked done in Emacs, which moved the deadline a week
+	require.Equal(t, weekly, tasks[0].due)
+	require.True(t, tasks[0].checked)
+	// Without the repeater it is a one-off task
+	require.Equal(t, Due{Date: "2024-01-20"}, tasks[1].due)
+	require.Equal(t, weekly, tasks[2].due)
+	require.True(t, tasks[2].checked)
+	// An earlier date keeps the recurrence
+	require.Equal(t, weekly, tasks[3].due)
+	require.False(t, tasks[3].checked)
+	require.Equal(t, 1, len(tasks[3].notes))
+
+	plan, err := planImport(tasks[:1], data)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(plan.commands))
+	require.Equal(t, "item_update", plan.commands[0].Type)
+	require.Equal(t, map[string]interface{}{"string": "every friday"}, plan.commands[0].Args["due"])
+	require.Equal(t, "item_close", plan.commands[1].Type)
+}
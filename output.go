@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output of `todui list` and `todui show`, selected with --output and --fields.
+// The schema is documented in the README, keep it in sync and only add to it.
+//
+//	text    aligned columns, the default
+//	tsv     tab separated values, one task per line
+//	json    an array of task objects, or one object for show
+//	ndjson  one task object per line
+
+const (
+	outputText   = "text"
+	outputTSV    = "tsv"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+var outputFormats = []string{outputText, outputTSV, outputJSON, outputNDJSON}
+
+// All fields, in the order they are written in json
+var taskFields = []string{"id", "content", "description", "project", "project_id", "section_id", "priority", "labels", "due", "checked", "children"}
+
+var defaultColumns = []string{"id", "due", "priority", "project", "content", "labels"}
+
+type output struct {
+	format string
+	fields []string
+}
+
+// Checks the format and the comma separated fields
+func parseOutput(format, fields string) (output, error) {
+	o := output{format: format}
+	if !Contains(outputFormats, format) {
+		return o, usageError("unknown output %q, expected one of %s", format, strings.Join(outputFormats, ", "))
+	}
+	for _, f := range strings.Split(fields, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !Contains(taskFields, f) {
+			return o, usageError("unknown field %q, expected some of %s", f, strings.Join(taskFields, ", "))
+		}
+		o.fields = append(o.fields, f)
+	}
+	if len(o.fields) == 0 {
+		o.fields = defaultColumns
+		if o.format == outputJSON || o.format == outputNDJSON {
+			o.fields = taskFields
+		}
+	}
+	return o, nil
+}
+
+func (o output) write(w io.Writer, todos []Todo) error {
+	switch o.format {
+	case outputJSON:
+		objects := make([]jsonObject, len(todos))
+		for i, t := range todos {
+			objects[i] = taskObject(t, o.fields)
+		}
+		return writeJSON(w, objects)
+	case outputNDJSON:
+		enc := json.NewEncoder(w)
+		for _, t := range todos {
+			err := enc.Encode(taskObject(t, o.fields))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputTSV:
+		for _, t := range todos {
+			values := make([]string, len(o.fields))
+			for i, f := range o.fields {
+				values[i] = tsvEscape(fieldValue(t, f))
+			}
+			_, err := fmt.Fprintln(w, strings.Join(values, "\t"))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, t := range todos {
+		values := make([]string, len(o.fields))
+		for i, f := range o.fields {
+			values[i] = textValue(t, f)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Writes a single task, as an object instead of an array in json
+func (o output) writeOne(w io.Writer, todo Todo) error {
+	if o.format == outputJSON {
+		return writeJSON(w, taskObject(todo, o.fields))
+	}
+	return o.write(w, []Todo{todo})
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+// A json object that keeps the order of its fields
+type jsonObject []jsonField
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		b.Write(name)
+		b.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+type jsonDue struct {
+	Date        string `json:"date"`
+	String      string `json:"string"`
+	IsRecurring bool   `json:"is_recurring"`
+	Timezone    string `json:"timezone"`
+}
+
+func taskObject(t Todo, fields []string) jsonObject {
+	o := make(jsonObject, 0, len(fields))
+	for _, f := range fields {
+		var v interface{}
+		switch f {
+		case "labels":
+			labels := t.Labels
+			if labels == nil {
+				labels = []string{}
+			}
+			v = labels
+		case "due":
+			if t.Due.Date != "" {
+				v = jsonDue{
+					Date:        t.Due.Date,
+					String:      t.Due.String,
+					IsRecurring: t.Due.IsRecurring,
+					Timezone:    t.Due.Timezone,
+				}
+			}
+		case "checked":
+			v = t.Checked
+		case "children":
+			children := make([]jsonObject, len(t.Children))
+			for i, c := range t.Children {
+				children[i] = taskObject(c, fields)
+			}
+			v = children
+		default:
+			v = fieldValue(t, f)
+		}
+		o = append(o, jsonField{name: f, value: v})
+	}
+	return o
+}
+
+// The field as a plain string, used for tsv and as the json value of the string fields
+func fieldValue(t Todo, field string) string {
+	switch field {
+	case "id":
+		return t.Id
+	case "content":
+		return t.Content
+	case "description":
+		return t.Description
+	case "project":
+		return t.ProjectName
+	case "project_id":
+		return t.ProjectId
+	case "section_id":
+		return t.SectionId
+	case "priority":
+		return renderPriority(t.Priority)
+	case "labels":
+		return strings.Join(t.Labels, ",")
+	case "due":
+		return t.Due.Date
+	case "checked":
+		return strconv.FormatBool(t.Checked)
+	case "children":
+		return strconv.Itoa(len(t.Children))
+	}
+	return ""
+}
+
+// Like the field value, but easier to read
+func textValue(t Todo, field string) string {
+	switch field {
+	case "project":
+		return "#" + t.ProjectName
+	case "priority":
+		return cliPriority(t.Priority)
+	case "labels":
+		return cliLabels(t.Labels)
+	case "description":
+		return tsvEscape(t.Description)
+	}
+	return fieldValue(t, field)
+}
+
+func tsvEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r").Replace(s)
+}
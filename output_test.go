@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var outputTodo = Todo{
+	Id:          "1",
+	ProjectId:   "11",
+	ProjectName: "Work",
+	Content:     "Write report",
+	Description: "line one\nline\ttwo",
+	Priority:    4,
+	Due:         Due{Date: "2024-01-01", String: "every monday", IsRecurring: true},
+	Children:    []Todo{{Id: "2", Content: "Draft", Priority: 1}},
+}
+
+func TestOutputJSON(t *testing.T) {
+	o, err := parseOutput(outputJSON, "")
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, o.write(&b, []Todo{outputTodo}))
+
+	var res []map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &res))
+	require.Equal(t, "p1", res[0]["priority"])
+	require.Equal(t, "Work", res[0]["project"])
+	require.Equal(t, []interface{}{}, res[0]["labels"])
+	require.Equal(t, true, res[0]["due"].(map[string]interface{})["is_recurring"])
+	child := res[0]["children"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "Draft", child["content"])
+	require.Nil(t, child["due"])
+}
+
+func TestOutputFields(t *testing.T) {
+	o, err := parseOutput(outputNDJSON, "content, id")
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, o.write(&b, []Todo{outputTodo, outputTodo.Children[0]}))
+	// In the given order
+	require.Equal(t, "{\"content\":\"Write report\",\"id\":\"1\"}\n{\"content\":\"Draft\",\"id\":\"2\"}\n", b.String())
+
+	b.Reset()
+	o, err = parseOutput(outputJSON, "id")
+	require.NoError(t, err)
+	require.NoError(t, o.writeOne(&b, outputTodo))
+	require.Equal(t, "{\n  \"id\": \"1\"\n}\n", b.String())
+
+	_, err = parseOutput(outputJSON, "id,nope")
+	require.ErrorIs(t, err, errUsage)
+	_, err = parseOutput("xml", "")
+	require.ErrorIs(t, err, errUsage)
+}
+
+func TestOutputTSV(t *testing.T) {
+	o, err := parseOutput(outputTSV, "id,priority,description,due,children")
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, o.write(&b, []Todo{outputTodo}))
+	require.Equal(t, "1\tp1\tline one\\nline\\ttwo\t2024-01-01\t1\n", b.String())
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sahilm/fuzzy"
+)
+
+// Command palette: `:` opens the input field, where any action can be run as "<command> <argument>".
+// Commands and arguments are fuzzy matched, so ":ta up" switches to the upcoming tab.
+// The matches for the word under the cursor are shown in the bottom bar, and tab completes the selected one.
+
+var inputFieldCommandPalette InputFieldCommand = "palette"
+
+const paletteSuggestions = 8
+
+type paletteCommand struct {
+	name string
+	desc string
+	// Completions of the argument, nil if the command takes no argument
+	args func(m model) []string
+	// The argument must be one of the completions
+	strict bool
+	run    func(m model, arg string) (model, tea.Cmd)
+}
+
+func paletteCommands() []paletteCommand {
+	return []paletteCommand{
+		{name: "sync", desc: "sync with todoist", run: func(m model, _ string) (model, tea.Cmd) {
+			m.syncing = true
+			m.syncError = nil
+			return m, m.fetchTodos
+		}},
+		{name: "tab", desc: "switch tab", args: func(model) []string { return tabNames }, strict: true, run: func(m model, arg string) (model, tea.Cmd) {
+			tab, _ := parseTab(arg)
+			m.setTab(tab)
+			return m, nil
+		}},
+		{name: "filter", desc: "filter tasks, empty to clear", args: func(model) []string { return []string{} }, run: func(m model, arg string) (model, tea.Cmd) {
+			m.currentFilter = arg
+			m.filteredTodos = filterContents(m.todos, arg)
+			m.filterLists()
+			m.refreshCursor()
+			return m, nil
+		}},
+		{name: "new", desc: "quick add a task", args: func(model) []string { return []string{} }, run: func(m model, arg string) (model, tea.Cmd) {
+			if arg == "" {
+				return m, nil
+			}
+			m.syncing = true
+			return m, m.quickAdd(arg)
+		}},
+		{name: "edit", desc: "edit task in editor", run: withTodo(func(m model, _ Todo, _ string) (model, tea.Cmd) {
+			return m.editCurrentTodo()
+		})},
+		{name: "done", desc: "mark task as done", run: withTodo(func(m model, todo Todo, _ string) (model, tea.Cmd) {
+			m.syncing = true
+			return m, m.markAsDone(todo)
+		})},
+		{name: "due", desc: "set due date, e.g. tomorrow 10:00", args: func(model) []string { return []string{"today", "tomorrow", "next week", "no date"} }, run: withTodo(func(m model, todo Todo, arg string) (model, tea.Cmd) {
+			todo.Due.ChangeString = arg
+			m.syncing = true
+			return m, m.editTask(EditTaskData{todo: todo})
+		})},
+		{name: "priority", desc: "set priority", args: func(model) []string { return []string{"p1", "p2", "p3", "p4"} }, strict: true, run: withTodo(func(m model, todo Todo, arg string) (model, tea.Cmd) {
+			todo.Priority = 5 - int(arg[1]-'0')
+			m.syncing = true
+			return m, m.editTask(EditTaskData{todo: todo})
+		})},
+		{name: "label", desc: "add or remove a label", args: paletteLabels, run: withTodo(func(m model, todo Todo, arg string) (model, tea.Cmd) {
+			if arg == "" {
+				return m, nil
+			}
+			labels := make([]string, 0, len(todo.Labels)+1)
+			for _, l := range todo.Labels {
+				if l != arg {
+					labels = append(labels, l)
+				}
+			}
+			if len(labels) == len(todo.Labels) {
+				labels = append(labels, arg)
+			}
+			todo.Labels = labels
+			m.syncing = true
+			return m, m.editTask(EditTaskData{todo: todo})
+		})},
+		{name: "move", desc: "move task to a section", args: paletteSections, strict: true, run: withTodo(func(m model, todo Todo, arg string) (model, tea.Cmd) {
+			sectionId := ""
+			for _, s := range m.sections {
+				if s.ProjectId == todo.ProjectId && s.Name == arg {
+					sectionId = s.Id
+				}
+			}
+			m.syncing = true
+			return m, m.moveToSection(todo, sectionId)
+		})},
+		{name: "sort", desc: "sort tasks", args: func(model) []string { return []string{sortDue, sortPriority, sortProject} }, strict: true, run: func(m model, arg string) (model, tea.Cmd) {
+			m.sortBy = arg
+			sortTodos(m.filteredTodos, m.sortBy)
+			m.filterLists()
+			return m, nil
+		}},
+		{name: "profile", desc: "switch profile", args: func(m model) []string { return m.config.profileNames() }, strict: true, run: func(m model, arg string) (model, tea.Cmd) {
+			if arg == m.profile {
+				return m, nil
+			}
+			if m.syncing || m.syncingInBackground {
+				m.syncError = fmt.Errorf("can not switch profile while syncing")
+				return m, nil
+			}
+			return m, m.switchProfile(arg)
+		}},
+		{name: "export", desc: "export the filtered tasks to todui.<ext>", args: func(model) []string { return transferFormatNames() }, strict: true, run: func(m model, arg string) (model, tea.Cmd) {
+			return m, m.exportTasks(arg)
+		}},
+		{name: "info", desc: "toggle task info", run: func(m model, _ string) (model, tea.Cmd) {
+			m.showInfo = !m.showInfo
+			return m, nil
+		}},
+		{name: "help", desc: "toggle help", run: func(m model, _ string) (model, tea.Cmd) {
+			m.showHelp = !m.showHelp
+			return m, nil
+		}},
+		{name: "quit", desc: "quit todui", run: func(m model, _ string) (model, tea.Cmd) {
+			return m, tea.Quit
+		}},
+	}
+}
+
+// Runs the command on the task under the cursor
+func withTodo(run func(m model, todo Todo, arg string) (model, tea.Cmd)) func(model, string) (model, tea.Cmd) {
+	return func(m model, arg string) (model, tea.Cmd) {
+		todo, err := m.getCurrentTodo()
+		if err != nil {
+			m.syncError = fmt.Errorf("no task selected")
+			return m, nil
+		}
+		return run(m, todo, arg)
+	}
+}
+
+// All known labels
+func paletteLabels(m model) []string {
+	labels := make([]string, 0, len(labelColors))
+	for l := range labelColors {
+		labels = append(labels, l)
+	}
+	for _, t := range m.todos {
+		for _, l := range t.Labels {
+			if !Contains(labels, l) {
+				labels = append(labels, l)
+			}
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Sections of the project of the task under the cursor
+func paletteSections(m model) []string {
+	todo, err := m.getCurrentTodo()
+	if err != nil {
+		return []string{}
+	}
+	sections := []string{"(no section)"}
+	for _, s := range m.sections {
+		if s.ProjectId == todo.ProjectId {
+			sections = append(sections, s.Name)
+		}
+	}
+	return sections
+}
+
+// Fuzzy matches, best first. All of the list matches an empty pattern.
+func fuzzyFind(pattern string, list []string) []string {
+	if pattern == "" {
+		return list
+	}
+	matches := fuzzy.Find(pattern, list)
+	res := make([]string, len(matches))
+	for i, match := range matches {
+		res[i] = match.Str
+	}
+	return res
+}
+
+// The command with the name, or the best match
+func findCommand(name string) (paletteCommand, bool) {
+	commands := paletteCommands()
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+		names[i] = c.name
+	}
+	matches := fuzzy.Find(name, names)
+	if len(matches) == 0 {
+		return paletteCommand{}, false
+	}
+	return commands[matches[0].Index], true
+}
+
+// The input before the word being completed, and the completions of the word
+func (m model) paletteCompletions() (string, []string) {
+	name, arg, hasArg := strings.Cut(m.textInput.Value(), " ")
+	if !hasArg {
+		names := make([]string, 0)
+		for _, c := range paletteCommands() {
+			names = append(names, c.name)
+		}
+		return "", fuzzyFind(name, names)
+	}
+	c, ok := findCommand(name)
+	if !ok || c.args == nil {
+		return "", []string{}
+	}
+	return c.name + " ", fuzzyFind(arg, c.args(m))
+}
+
+func (m *model) openPalette() {
+	m.notice = ""
+	m.textInput.Focus()
+	m.textInput.SetValue("")
+	m.textInput.Placeholder = ""
+	m.textInput.Prompt = ":"
+	m.inputField.enabled = true
+	m.inputField.command = inputFieldCommandPalette
+	m.paletteSelected = 0
+}
+
+func (m *model) closePalette() {
+	m.textInput.SetValue("")
+	m.textInput.Prompt = ""
+	m.inputField.enabled = false
+	m.inputField.command = ""
+}
+
+func (m model) updatePalette(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.keys.SetInput):
+			value := strings.TrimSpace(m.textInput.Value())
+			m.closePalette()
+			return m.runPaletteCommand(value)
+		case key.Matches(msg, m.keys.ClearInput, m.keys.ExitInput):
+			m.closePalette()
+			return m, nil
+		case key.Matches(msg, m.keys.Complete):
+			prefix, matches := m.paletteCompletions()
+			if m.paletteSelected < len(matches) {
+				value := prefix + matches[m.paletteSelected]
+				if c, ok := findCommand(value); prefix == "" && ok && c.args != nil {
+					value += " "
+				}
+				m.textInput.SetValue(value)
+				m.textInput.CursorEnd()
+				m.paletteSelected = 0
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.NextSuggestion):
+			_, matches := m.paletteCompletions()
+			if m.paletteSelected < len(matches)-1 && m.paletteSelected < paletteSuggestions-1 {
+				m.paletteSelected++
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.PrevSuggestion):
+			if m.paletteSelected > 0 {
+				m.paletteSelected--
+			}
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	m.paletteSelected = 0
+	return m, cmd
+}
+
+func (m model) runPaletteCommand(value string) (tea.Model, tea.Cmd) {
+	if value == "" {
+		return m, nil
+	}
+	name, arg, _ := strings.Cut(value, " ")
+	arg = strings.TrimSpace(arg)
+	c, ok := findCommand(name)
+	if !ok {
+		m.syncError = fmt.Errorf("unknown command %q", name)
+		return m, nil
+	}
+	if c.strict {
+		matches := fuzzyFind(arg, c.args(m))
+		if arg == "" || len(matches) == 0 {
+			m.syncError = fmt.Errorf("%s: expected one of %s", c.name, strings.Join(c.args(m), ", "))
+			return m, nil
+		}
+		if !Contains(matches, arg) {
+			arg = matches[0]
+		}
+	}
+	return c.run(m, arg)
+}
+
+// The completions, shown instead of the help in the bottom bar
+func (m model) renderPaletteSuggestions() string {
+	prefix, matches := m.paletteCompletions()
+	if len(matches) > paletteSuggestions {
+		matches = matches[:paletteSuggestions]
+	}
+	s := ""
+	for i, match := range matches {
+		if i == m.paletteSelected {
+			s += chosenTextStyle.Render(match)
+			if c, ok := findCommand(match); prefix == "" && ok {
+				s += dimTextStyle.Render(" " + c.desc)
+			}
+		} else {
+			s += dimTextStyle.Render(match)
+		}
+		if i+1 != len(matches) {
+			s += "   "
+		}
+	}
+	return s
+}
+
+type Exported struct {
+	path  string
+	tasks int
+	err   error
+}
+
+// Exports the tasks of the local db that match the filter, like export --local, to todui.<ext> in the working directory.
+// An existing file is not overwritten.
+func (m model) exportTasks(format string) tea.Cmd {
+	return func() tea.Msg {
+		f, err := findTransferFormat(format)
+		if err != nil {
+			return Exported{err: err}
+		}
+		data, err := cli{storage: m.storage, config: m.config}.exportData(true)
+		if err != nil {
+			return Exported{err: err}
+		}
+		data.completed = nil
+		data.filter(m.currentFilter)
+		path := "todui." + f.ext
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			return Exported{err: fmt.Errorf("%s already exists", path)}
+		}
+		if err != nil {
+			return Exported{err: err}
+		}
+		err = f.export(file, data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return Exported{path: path, tasks: len(data.todos), err: err}
+	}
+}
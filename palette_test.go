@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+func typeText(m model, s string) model {
+	var res tea.Model = m
+	for _, r := range s {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
+		if r == ' ' {
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{r}}
+		}
+		res, _ = res.Update(msg)
+	}
+	return res.(model)
+}
+
+func sendKey(m model, t tea.KeyType) (model, tea.Cmd) {
+	res, cmd := m.Update(tea.KeyMsg{Type: t})
+	return res.(model), cmd
+}
+
+func TestFindCommand(t *testing.T) {
+	c, ok := findCommand("sync")
+	require.True(t, ok)
+	require.Equal(t, "sync", c.name)
+	c, ok = findCommand("prio")
+	require.True(t, ok)
+	require.Equal(t, "priority", c.name)
+	_, ok = findCommand("xyz")
+	require.False(t, ok)
+
+	require.Equal(t, []string{"upcoming"}, fuzzyFind("upc", tabNames))
+	require.Equal(t, tabNames, fuzzyFind("", tabNames))
+}
+
+func TestPaletteTab(t *testing.T) {
+	m := newScrollModel(10)
+	m = press(m, ":")
+	require.True(t, m.inputField.enabled)
+	m = typeText(m, "ta")
+	require.Contains(t, m.View(), "switch tab")
+
+	// Completes the command, and then the argument
+	m, _ = sendKey(m, tea.KeyTab)
+	require.Equal(t, "tab ", m.textInput.Value())
+	m = typeText(m, "cal")
+	m, _ = sendKey(m, tea.KeyTab)
+	require.Equal(t, "tab calendar", m.textInput.Value())
+	m, _ = sendKey(m, tea.KeyEnter)
+	require.False(t, m.inputField.enabled)
+	require.Equal(t, calendarTab, m.tab)
+
+	// Fuzzy matched without completing
+	m = press(m, ":")
+	m = typeText(m, "ta upc")
+	m, _ = sendKey(m, tea.KeyEnter)
+	require.Equal(t, upcomingTab, m.tab)
+}
+
+func TestPaletteSuggestions(t *testing.T) {
+	m := newScrollModel(10)
+	m = press(m, ":")
+	m = typeText(m, "sort ")
+	_, matches := m.paletteCompletions()
+	require.Equal(t, []string{"due", "priority", "project"}, matches)
+
+	m, _ = sendKey(m, tea.KeyDown)
+	m, _ = sendKey(m, tea.KeyTab)
+	require.Equal(t, "sort priority", m.textInput.Value())
+	m, _ = sendKey(m, tea.KeyEnter)
+	require.Equal(t, sortPriority, m.sortBy)
+
+	// Escape closes the palette, and keeps the filter
+	m.currentFilter = "task"
+	m = press(m, ":")
+	m, _ = sendKey(m, tea.KeyEsc)
+	require.False(t, m.inputField.enabled)
+	require.Equal(t, "task", m.currentFilter)
+}
+
+func TestPaletteCommands(t *testing.T) {
+	m := newScrollModel(20)
+	m = press(m, ":")
+	m = typeText(m, "filter 1")
+	m, _ = sendKey(m, tea.KeyEnter)
+	require.Equal(t, "1", m.currentFilter)
+	require.Equal(t, 11, len(m.filteredTodos))
+
+	m = press(m, ":")
+	m = typeText(m, "nope")
+	m, _ = sendKey(m, tea.KeyEnter)
+	require.Error(t, m.syncError)
+
+	m = press(m, ":")
+	m = typeText(m, "priority p9")
+	m, cmd := sendKey(m, tea.KeyEnter)
+	require.Nil(t, cmd)
+	require.Error(t, m.syncError)
+
+	m = press(m, ":")
+	m = typeText(m, "q")
+	_, cmd = sendKey(m, tea.KeyEnter)
+	require.NotNil(t, cmd)
+	require.Equal(t, tea.Quit(), cmd())
+}
+
+func TestPaletteExport(t *testing.T) {
+	c, _, _ := newTestCLI(t)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	m := newScrollModel(3)
+	m.storage = c.storage
+	m.currentFilter = "#Home"
+	m = press(m, ":")
+	m = typeText(m, "export tod")
+	m, _ = sendKey(m, tea.KeyTab)
+	require.Equal(t, "export todotxt", m.textInput.Value())
+	m, cmd := sendKey(m, tea.KeyEnter)
+	require.NotNil(t, cmd)
+	m = update(m, cmd())
+	require.NoError(t, m.syncError)
+	require.Equal(t, "exported 1 tasks to todui.txt", m.notice)
+	b, err := os.ReadFile("todui.txt")
+	require.NoError(t, err)
+	require.Equal(t, "(A) Buy milk +Home @errand due:2024-01-02\n", string(b))
+
+	// The file is not overwritten
+	m = press(m, ":")
+	require.Empty(t, m.notice)
+	m = typeText(m, "export todotxt")
+	m, cmd = sendKey(m, tea.KeyEnter)
+	m = update(m, cmd())
+	require.EqualError(t, m.syncError, "export: todui.txt already exists")
+	b, err = os.ReadFile("todui.txt")
+	require.NoError(t, err)
+	require.Equal(t, "(A) Buy milk +Home @errand due:2024-01-02\n", string(b))
+
+	m = press(m, ":")
+	m = typeText(m, "export xml")
+	m, _ = sendKey(m, tea.KeyEnter)
+	require.Error(t, m.syncError)
+}
+
+func TestPaletteEditRecurring(t *testing.T) {
+	c, _, _ := newTestCLI(t)
+	edits := fakeTodoist(t)
+	err := c.storage.db.InsertFromSync(context.Background(), SyncResponse{
+		SyncToken: "testing",
+		Items: []Item{{Id: "4", ProjectId: "11", Content: "Water plants", Priority: 1,
+			Due: Due{Date: "2024-01-05", String: "every friday", IsRecurring: true}}},
+	})
+	require.NoError(t, err)
+	todos, err := c.storage.localTodos()
+	require.NoError(t, err)
+	m := newScrollModel(0)
+	m.storage = c.storage
+	m = update(m, FetchedTodos{data: todos})
+
+	// The priority and the labels are sent without the due, so the task keeps recurring
+	for _, command := range []string{"filter water", "priority p1", "label home"} {
+		m = press(m, ":")
+		m = typeText(m, command)
+		var cmd tea.Cmd
+		m, cmd = sendKey(m, tea.KeyEnter)
+		if cmd != nil {
+			m = update(m, cmd())
+		}
+		require.NoError(t, m.syncError)
+	}
+	require.Equal(t, 2, len(*edits))
+	for _, body := range *edits {
+		require.NotContains(t, body, `"due_`)
+	}
+	require.Contains(t, (*edits)[0], `"priority":4`)
+	require.Contains(t, (*edits)[1], `"labels":["home"]`)
+}
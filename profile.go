@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Profiles are separate accounts, each with its own token and database:
+//
+//	profile = "work"  # started by default
+//
+//	[profiles.work]
+//	token_command = "pass show todoist/work"
+//
+// The token settings at the top of the config file are the default profile, which is also the only one
+// that takes TODOIST_API_TOKEN and the -t and -d flags.
+// Profiles without paths gets their own token file and database in the XDG directories.
+
+const defaultProfile = "default"
+
+type Profile struct {
+	Token        string `toml:"token"`
+	TokenCommand string `toml:"token_command"`
+	TokenPath    string `toml:"token_path"`
+	DBPath       string `toml:"db_path"`
+	// TODOIST_API_TOKEN is only used for the default profile, so it can not sync another account into a profile
+	tokenFromEnv bool
+}
+
+// Names of the profiles, the default profile first
+func (c Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		if name != defaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{defaultProfile}, names...)
+}
+
+func (c Config) getProfile(name string) (Profile, error) {
+	if name == "" || name == defaultProfile {
+		p := Profile{
+			Token:        c.Token,
+			TokenCommand: c.TokenCommand,
+			TokenPath:    c.TokenPath,
+			DBPath:       c.DBPath,
+			tokenFromEnv: true,
+		}
+		return p, p.defaultPaths("")
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return p, fmt.Errorf("unknown profile %q", name)
+	}
+	return p, p.defaultPaths(name)
+}
+
+// Fills in the token and db paths that are not set.
+// The default profile keeps using the files at the old paths, if they exist.
+func (p *Profile) defaultPaths(name string) error {
+	config, err := configDir()
+	if err != nil {
+		return err
+	}
+	data, err := dataDir()
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		if p.TokenPath == "" {
+			p.TokenPath = filepath.Join(config, "profiles", name, "token")
+		}
+		if p.DBPath == "" {
+			p.DBPath = filepath.Join(data, "profiles", name, "todui.db")
+		}
+		return nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	if p.TokenPath == "" {
+		p.TokenPath = withLegacyPath(filepath.Join(config, "token"), filepath.Join(home, ".todoist.token"))
+	}
+	if p.DBPath == "" {
+		p.DBPath = withLegacyPath(filepath.Join(data, "todui.db"), filepath.Join(home, ".cache", "todui.db"))
+	}
+	return nil
+}
+
+// Opens the database of the profile. The returned error is errNoToken when only the token is missing,
+// the storage can then be used once a token is set up.
+func openStorage(p Profile) (Storage, error) {
+	db, err := NewDB(p.DBPath)
+	if err != nil {
+		return Storage{}, err
+	}
+	token, err := resolveToken(p)
+	if err != nil && !errors.Is(err, errNoToken) {
+		db.Close()
+		return Storage{}, err
+	}
+	return Storage{
+		api: NewAPI(token),
+		db:  db,
+	}, err
+}
+
+type ProfileSwitched struct {
+	name      string
+	storage   Storage
+	tokenPath string
+	noToken   bool
+}
+
+func (m model) switchProfile(name string) tea.Cmd {
+	return func() tea.Msg {
+		p, err := m.config.getProfile(name)
+		if err != nil {
+			return SyncError{
+				err: err,
+			}
+		}
+		storage, err := openStorage(p)
+		if err != nil && !errors.Is(err, errNoToken) {
+			return SyncError{
+				err: err,
+			}
+		}
+		return ProfileSwitched{
+			name:      name,
+			storage:   storage,
+			tokenPath: p.TokenPath,
+			noToken:   err != nil,
+		}
+	}
+}
+
+// The profile after the current one
+func (m model) nextProfile() string {
+	names := m.config.profileNames()
+	for i, name := range names {
+		if name == m.profile {
+			return names[(i+1)%len(names)]
+		}
+	}
+	return names[0]
+}
+
+// Replaces the storage, and starts over with the tasks of the new profile
+func (m model) useProfile(msg ProfileSwitched) (model, tea.Cmd) {
+	if m.storage.db.conn != nil {
+		m.storage.db.Close()
+	}
+	m.storage = msg.storage
+	m.profile = msg.name
+	m.tokenPath = msg.tokenPath
+	m.todos = []Todo{}
+	m.filteredTodos = []Todo{}
+	m.completedTodos = []Todo{}
+	m.filterLists()
+	m.sections = []Section{}
+	m.board = board{}
+	m.cursor.index = 0
+	m.offset = 0
+	m.syncError = nil
+	setLabelColors(nil)
+	if msg.noToken {
+		m.startSetup()
+		return m, nil
+	}
+	m.syncing = true
+	return m, m.getLocalTodos
+}
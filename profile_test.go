@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+
+	p := Profile{}
+	require.NoError(t, p.defaultPaths(""))
+	require.Equal(t, filepath.Join(home, ".config", "todui", "token"), p.TokenPath)
+	require.Equal(t, filepath.Join(home, "data", "todui", "todui.db"), p.DBPath)
+
+	// Files at the old paths are still used
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".todoist.token"), []byte("token"), 0600))
+	p = Profile{}
+	require.NoError(t, p.defaultPaths(""))
+	require.Equal(t, filepath.Join(home, ".todoist.token"), p.TokenPath)
+
+	// Unless there is one at the new path
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".config", "todui"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".config", "todui", "token"), []byte("token"), 0600))
+	p = Profile{}
+	require.NoError(t, p.defaultPaths(""))
+	require.Equal(t, filepath.Join(home, ".config", "todui", "token"), p.TokenPath)
+
+	// Set paths are kept
+	p = Profile{DBPath: "/somewhere/todui.db"}
+	require.NoError(t, p.defaultPaths(""))
+	require.Equal(t, "/somewhere/todui.db", p.DBPath)
+
+	// Named profiles have their own files
+	p = Profile{}
+	require.NoError(t, p.defaultPaths("work"))
+	require.Equal(t, filepath.Join(home, ".config", "todui", "profiles", "work", "token"), p.TokenPath)
+	require.Equal(t, filepath.Join(home, "data", "todui", "profiles", "work", "todui.db"), p.DBPath)
+}
+
+func TestGetProfile(t *testing.T) {
+	config := defaultConfig()
+	config.TokenCommand = "echo personal"
+	config.DBPath = "/tmp/personal.db"
+	config.Profiles = map[string]Profile{
+		"work": {Token: "work", DBPath: "/tmp/work.db"},
+		"club": {},
+	}
+	require.Equal(t, []string{"default", "club", "work"}, config.profileNames())
+
+	p, err := config.getProfile(defaultProfile)
+	require.NoError(t, err)
+	require.Equal(t, "echo personal", p.TokenCommand)
+	require.Equal(t, "/tmp/personal.db", p.DBPath)
+
+	p, err = config.getProfile("work")
+	require.NoError(t, err)
+	require.Equal(t, "work", p.Token)
+	t.Setenv("TODOIST_API_TOKEN", "personal")
+	token, err := resolveToken(p)
+	require.NoError(t, err)
+	require.Equal(t, "work", token)
+
+	_, err = config.getProfile("nope")
+	require.Error(t, err)
+}
+
+func TestSwitchProfile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TODOIST_API_TOKEN", "")
+	config := defaultConfig()
+	config.Token = "personal"
+	config.DBPath = filepath.Join(dir, "personal.db")
+	config.Profiles = map[string]Profile{
+		"work": {DBPath: filepath.Join(dir, "work.db"), TokenPath: filepath.Join(dir, "work.token")},
+	}
+	p, err := config.getProfile(defaultProfile)
+	require.NoError(t, err)
+	storage, err := openStorage(p)
+	require.NoError(t, err)
+
+	m := NewModel(storage, false)
+	m.config = config
+	m.profile = defaultProfile
+	m.syncing = false
+	m.todos = []Todo{{Id: "1", Content: "personal task"}}
+	require.Equal(t, "work", m.nextProfile())
+
+	var res tea.Model = m
+	res, cmd := res.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("P")})
+	require.NotNil(t, cmd)
+	res, _ = res.Update(cmd())
+	m = res.(model)
+	require.Equal(t, "work", m.profile)
+	require.Empty(t, m.todos)
+	// The work profile has no token yet
+	require.True(t, m.setup)
+	require.Equal(t, filepath.Join(dir, "work.token"), m.tokenPath)
+	require.Equal(t, defaultProfile, m.nextProfile())
+	m.storage.db.Close()
+
+	_, err = openStorage(Profile{DBPath: filepath.Join(dir, "x.db"), TokenPath: filepath.Join(dir, "none")})
+	require.True(t, errors.Is(err, errNoToken))
+}
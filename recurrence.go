@@ -0,0 +1,455 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local evaluation of todoist recurring due strings.
+// Used to roll a recurring task forward when it is completed, so we dont
+// have to wait for the server to do it. The server result always wins on
+// the next sync.
+//
+// https://todoist.com/help/articles/introduction-to-recurring-dates
+//
+// Only english due strings are supported for now.
+
+type recurrenceUnit int
+
+const (
+	unitDay recurrenceUnit = iota
+	unitWeek
+	unitMonth
+	unitYear
+)
+
+type recurrence struct {
+	interval       int
+	unit           recurrenceUnit
+	weekdays       []time.Weekday
+	monthDays      []int // -1 is the last day of the month
+	month          time.Month
+	fromCompletion bool // every! and after
+	hasTime        bool
+	hour           int
+	minute         int
+}
+
+var (
+	recurrenceTimeRegex     = regexp.MustCompile(`\s+(?:at|@)\s+(\d{1,2})(?::(\d{2}))?\s*(am|pm)?$`)
+	recurrenceStartingRegex = regexp.MustCompile(`\s+(?:starting|from)\s+.*$`)
+	recurrenceIntervalRegex = regexp.MustCompile(`^(\d+)\s+(\w+)$`)
+	recurrenceMonthDayRegex = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+
+	weekdayNames = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+
+	monthNames = map[string]time.Month{
+		"january": time.January, "jan": time.January,
+		"february": time.February, "feb": time.February,
+		"march": time.March, "mar": time.March,
+		"april": time.April, "apr": time.April,
+		"may":  time.May,
+		"june": time.June, "jun": time.June,
+		"july": time.July, "jul": time.July,
+		"august": time.August, "aug": time.August,
+		"september": time.September, "sep": time.September, "sept": time.September,
+		"october": time.October, "oct": time.October,
+		"november": time.November, "nov": time.November,
+		"december": time.December, "dec": time.December,
+	}
+)
+
+func parseRecurrence(s string) (recurrence, error) {
+	r := recurrence{interval: 1}
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.Join(strings.Fields(s), " ")
+
+	if strings.Contains(s, " until ") || strings.Contains(s, " ending ") || strings.Contains(s, " for ") {
+		return r, fmt.Errorf("recurrence: end dates are not supported: %s", s)
+	}
+	s = recurrenceStartingRegex.ReplaceAllString(s, "")
+	if m := recurrenceTimeRegex.FindStringSubmatch(s); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2]) // empty string gives 0
+		if m[3] == "pm" && hour < 12 {
+			hour += 12
+		}
+		if m[3] == "am" && hour == 12 {
+			hour = 0
+		}
+		if hour > 23 || minute > 59 {
+			return r, fmt.Errorf("recurrence: invalid time: %s", s)
+		}
+		r.hasTime, r.hour, r.minute = true, hour, minute
+		s = recurrenceTimeRegex.ReplaceAllString(s, "")
+	}
+
+	switch s {
+	case "daily":
+		s = "every day"
+	case "weekly":
+		s = "every week"
+	case "monthly":
+		s = "every month"
+	case "yearly", "annually":
+		s = "every year"
+	}
+
+	var rest string
+	switch {
+	case strings.HasPrefix(s, "every! "):
+		r.fromCompletion = true
+		rest = strings.TrimPrefix(s, "every! ")
+	case strings.HasPrefix(s, "after "):
+		r.fromCompletion = true
+		rest = strings.TrimPrefix(s, "after ")
+	case strings.HasPrefix(s, "every "):
+		rest = strings.TrimPrefix(s, "every ")
+	default:
+		return r, fmt.Errorf("recurrence: not a recurring due string: %s", s)
+	}
+
+	if strings.HasPrefix(rest, "other ") {
+		r.interval = 2
+		rest = strings.TrimPrefix(rest, "other ")
+	}
+	if m := recurrenceIntervalRegex.FindStringSubmatch(rest); m != nil {
+		if _, ok := parseUnit(m[2]); ok {
+			r.interval, _ = strconv.Atoi(m[1])
+			rest = m[2]
+		}
+	}
+	if r.interval < 1 {
+		return r, fmt.Errorf("recurrence: invalid interval: %s", s)
+	}
+
+	if unit, ok := parseUnit(rest); ok {
+		r.unit = unit
+		return r, nil
+	}
+
+	switch rest {
+	case "morning":
+		r.unit = unitDay
+		if !r.hasTime {
+			r.hasTime, r.hour = true, 9
+		}
+		return r, nil
+	case "evening", "night":
+		r.unit = unitDay
+		if !r.hasTime {
+			r.hasTime, r.hour = true, 19
+		}
+		return r, nil
+	case "weekday", "workday", "work day":
+		r.unit = unitWeek
+		r.weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		return r, nil
+	case "weekend":
+		r.unit = unitWeek
+		r.weekdays = []time.Weekday{time.Saturday, time.Sunday}
+		return r, nil
+	case "last day", "last day of the month":
+		r.unit = unitMonth
+		r.monthDays = []int{-1}
+		return r, nil
+	}
+
+	parts := splitRecurrenceList(rest)
+
+	// every monday, wed and fri
+	if weekdays, ok := parseWeekdays(parts); ok {
+		r.unit = unitWeek
+		r.weekdays = weekdays
+		return r, nil
+	}
+
+	// every 1st, 15th
+	if days, ok := parseMonthDays(parts); ok {
+		r.unit = unitMonth
+		r.monthDays = days
+		return r, nil
+	}
+
+	// every jan 5 / every 5 jan
+	fields := strings.Fields(rest)
+	if len(fields) == 2 {
+		month, okMonth := monthNames[fields[0]]
+		day, okDay := parseMonthDay(fields[1])
+		if !okMonth {
+			month, okMonth = monthNames[fields[1]]
+			day, okDay = parseMonthDay(fields[0])
+		}
+		if okMonth && okDay {
+			r.unit = unitYear
+			r.month = month
+			r.monthDays = []int{day}
+			return r, nil
+		}
+	}
+
+	return r, fmt.Errorf("recurrence: unsupported due string: %s", s)
+}
+
+func parseUnit(s string) (recurrenceUnit, bool) {
+	switch s {
+	case "day", "days":
+		return unitDay, true
+	case "week", "weeks":
+		return unitWeek, true
+	case "month", "months":
+		return unitMonth, true
+	case "year", "years":
+		return unitYear, true
+	}
+	return unitDay, false
+}
+
+func splitRecurrenceList(s string) []string {
+	s = strings.ReplaceAll(s, " and ", ",")
+	parts := make([]string, 0)
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+func parseWeekdays(parts []string) ([]time.Weekday, bool) {
+	if len(parts) == 0 {
+		return nil, false
+	}
+	weekdays := make([]time.Weekday, 0, len(parts))
+	for _, p := range parts {
+		w, ok := weekdayNames[p]
+		if !ok {
+			return nil, false
+		}
+		weekdays = append(weekdays, w)
+	}
+	return weekdays, true
+}
+
+func parseMonthDays(parts []string) ([]int, bool) {
+	if len(parts) == 0 {
+		return nil, false
+	}
+	days := make([]int, 0, len(parts))
+	for _, p := range parts {
+		if p == "last day" || p == "last" {
+			days = append(days, -1)
+			continue
+		}
+		d, ok := parseMonthDay(p)
+		if !ok {
+			return nil, false
+		}
+		days = append(days, d)
+	}
+	return days, true
+}
+
+func parseMonthDay(s string) (int, bool) {
+	m := recurrenceMonthDayRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	d, _ := strconv.Atoi(m[1])
+	if d < 1 || d > 31 {
+		return 0, false
+	}
+	return d, true
+}
+
+// Returns the next occurrence after completing a task that was due at `due`.
+// For "every" the pattern is anchored on the due date, and we skip ahead
+// until the next occurrence is in the future. For "every!"/"after" the next
+// occurrence is counted from the completion time.
+func (r recurrence) next(due time.Time, completedAt time.Time, withTime bool) time.Time {
+	if r.hasTime {
+		due = time.Date(due.Year(), due.Month(), due.Day(), r.hour, r.minute, 0, 0, due.Location())
+		withTime = true
+	}
+	base := due
+	if r.fromCompletion {
+		c := completedAt.In(due.Location())
+		base = time.Date(c.Year(), c.Month(), c.Day(), due.Hour(), due.Minute(), due.Second(), 0, due.Location())
+	}
+
+	// The next occurrence has to be after both the base and "now"
+	cutoff := completedAt.In(due.Location())
+	if !withTime {
+		cutoff = time.Date(cutoff.Year(), cutoff.Month(), cutoff.Day(), 0, 0, 0, 0, due.Location())
+	}
+	if base.After(cutoff) {
+		cutoff = base
+	}
+
+	if len(r.weekdays) == 0 && len(r.monthDays) == 0 {
+		n := 1
+		t := r.add(base, n)
+		for !r.fromCompletion && !t.After(cutoff) {
+			n++
+			t = r.add(base, n)
+		}
+		return t
+	}
+
+	// Walk day by day until the pattern matches. Bounded, so a broken pattern cant hang the tui.
+	t := base
+	for i := 0; i < 366*4*r.interval; i++ {
+		t = t.AddDate(0, 0, 1)
+		if t.After(cutoff) && r.matches(base, t) {
+			return t
+		}
+	}
+	return r.add(base, 1)
+}
+
+// Adds n intervals to t, keeping the day of month where possible (jan 31 + 1 month = feb 28)
+func (r recurrence) add(t time.Time, n int) time.Time {
+	switch r.unit {
+	case unitWeek:
+		return t.AddDate(0, 0, 7*r.interval*n)
+	case unitMonth:
+		return addMonths(t, r.interval*n)
+	case unitYear:
+		return addMonths(t, 12*r.interval*n)
+	default:
+		return t.AddDate(0, 0, r.interval*n)
+	}
+}
+
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	day := t.Day()
+	if last := daysInMonth(first); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+func daysInMonth(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+func (r recurrence) matches(base time.Time, t time.Time) bool {
+	switch r.unit {
+	case unitWeek:
+		if !Contains(r.weekdays, t.Weekday()) {
+			return false
+		}
+		return weeksBetween(base, t)%r.interval == 0
+	case unitMonth:
+		if !matchesMonthDay(r.monthDays, t) {
+			return false
+		}
+		months := (t.Year()-base.Year())*12 + int(t.Month()-base.Month())
+		return months%r.interval == 0
+	case unitYear:
+		if t.Month() != r.month || !matchesMonthDay(r.monthDays, t) {
+			return false
+		}
+		return (t.Year()-base.Year())%r.interval == 0
+	}
+	return true
+}
+
+func matchesMonthDay(days []int, t time.Time) bool {
+	last := daysInMonth(t)
+	for _, d := range days {
+		if d == -1 && t.Day() == last {
+			return true
+		}
+		// every 31st falls on the last day in shorter months
+		if d == t.Day() || (d > last && t.Day() == last) {
+			return true
+		}
+	}
+	return false
+}
+
+// Number of whole weeks between the weeks (starting monday) of a and b
+func weeksBetween(a, b time.Time) int {
+	start := func(t time.Time) time.Time {
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+	}
+	return int(start(b).Sub(start(a)).Hours()/24) / 7
+}
+
+// Computes the next due date of a recurring task completed at `completedAt`,
+// formatted the same way as the original due date.
+func nextDueDate(due Due, completedAt time.Time) (string, error) {
+	if !due.IsRecurring {
+		return "", fmt.Errorf("recurrence: task is not recurring")
+	}
+	if due.Lang != "" && due.Lang != "en" {
+		return "", fmt.Errorf("recurrence: language %s is not supported", due.Lang)
+	}
+	r, err := parseRecurrence(due.String)
+	if err != nil {
+		return "", err
+	}
+	parsed, hasTime, err := parseDueDate(due.Date, due.Timezone)
+	if err != nil {
+		return "", err
+	}
+	next := r.next(parsed, completedAt, hasTime)
+	return formatDueDate(next, due.Date, hasTime || r.hasTime), nil
+}
+
+var rruleDays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// The iCalendar RRULE of the recurrence. The time of day is the one of the start date.
+// Recurring from the completion date can not be expressed.
+func (r recurrence) rrule() (string, bool) {
+	if r.fromCompletion {
+		return "", false
+	}
+	parts := make([]string, 0)
+	switch r.unit {
+	case unitDay:
+		parts = append(parts, "FREQ=DAILY")
+	case unitWeek:
+		parts = append(parts, "FREQ=WEEKLY")
+	case unitMonth:
+		parts = append(parts, "FREQ=MONTHLY")
+	case unitYear:
+		parts = append(parts, "FREQ=YEARLY")
+	}
+	if r.interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.interval))
+	}
+	if len(r.weekdays) > 0 {
+		days := make([]string, len(r.weekdays))
+		for i, d := range r.weekdays {
+			days[i] = rruleDays[d]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.unit == unitYear && r.month != 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTH=%d", r.month))
+	}
+	if len(r.monthDays) > 0 {
+		days := make([]string, len(r.monthDays))
+		for i, d := range r.monthDays {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";"), true
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := parseRecurrence("every 3 days")
+	require.NoError(t, err)
+	require.Equal(t, 3, r.interval)
+	require.Equal(t, unitDay, r.unit)
+
+	r, err = parseRecurrence("Every other Monday")
+	require.NoError(t, err)
+	require.Equal(t, 2, r.interval)
+	require.Equal(t, []time.Weekday{time.Monday}, r.weekdays)
+
+	r, err = parseRecurrence("every mon, wed and fri at 9:30pm")
+	require.NoError(t, err)
+	require.Equal(t, []time.Weekday{time.Monday, time.Wednesday, time.Friday}, r.weekdays)
+	require.True(t, r.hasTime)
+	require.Equal(t, 21, r.hour)
+	require.Equal(t, 30, r.minute)
+
+	r, err = parseRecurrence("every! 2 weeks")
+	require.NoError(t, err)
+	require.True(t, r.fromCompletion)
+	require.Equal(t, unitWeek, r.unit)
+
+	r, err = parseRecurrence("every 1st, 15th")
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 15}, r.monthDays)
+
+	r, err = parseRecurrence("every jan 5")
+	require.NoError(t, err)
+	require.Equal(t, unitYear, r.unit)
+	require.Equal(t, time.January, r.month)
+
+	_, err = parseRecurrence("every day until feb 1")
+	require.Error(t, err)
+
+	_, err = parseRecurrence("tomorrow")
+	require.Error(t, err)
+}
+
+func TestNextDueDate(t *testing.T) {
+	cases := []struct {
+		due       Due
+		completed string
+		expected  string
+	}{
+		{Due{String: "every day", Date: "2024-01-10"}, "2024-01-10 12:00", "2024-01-11"},
+		// Overdue tasks are rolled into the future
+		{Due{String: "every day", Date: "2024-01-01"}, "2024-01-10 12:00", "2024-01-11"},
+		{Due{String: "every 3 days", Date: "2024-01-01"}, "2024-01-10 12:00", "2024-01-13"},
+		// Completed early, every is anchored on the due date
+		{Due{String: "every week", Date: "2024-01-10"}, "2024-01-08 12:00", "2024-01-17"},
+		// every! counts from the completion date
+		{Due{String: "every! 3 days", Date: "2024-01-01"}, "2024-01-10 12:00", "2024-01-13"},
+		{Due{String: "after 1 week", Date: "2024-01-10"}, "2024-01-08 12:00", "2024-01-15"},
+		{Due{String: "every monday", Date: "2024-01-08"}, "2024-01-08 12:00", "2024-01-15"},
+		{Due{String: "every other monday", Date: "2024-01-08"}, "2024-01-08 12:00", "2024-01-22"},
+		{Due{String: "every weekday", Date: "2024-01-12"}, "2024-01-12 12:00", "2024-01-15"},
+		{Due{String: "every month", Date: "2024-01-31"}, "2024-01-31 12:00", "2024-02-29"},
+		{Due{String: "every 15th", Date: "2024-01-15"}, "2024-01-15 12:00", "2024-02-15"},
+		{Due{String: "every last day", Date: "2024-01-31"}, "2024-01-31 12:00", "2024-02-29"},
+		{Due{String: "every year", Date: "2024-02-29"}, "2024-02-29 12:00", "2025-02-28"},
+		{Due{String: "every jan 5", Date: "2024-01-05"}, "2024-01-05 12:00", "2025-01-05"},
+		{Due{String: "every day at 9am", Date: "2024-01-10T09:00:00"}, "2024-01-10 10:00", "2024-01-11T09:00:00"},
+		// Completed before the time today, still rolls to tomorrow
+		{Due{String: "every day at 9am", Date: "2024-01-10T09:00:00"}, "2024-01-10 08:00", "2024-01-11T09:00:00"},
+		{Due{String: "every day at 9am", Date: "2024-01-10"}, "2024-01-10 08:00", "2024-01-11T09:00:00"},
+	}
+	for _, c := range cases {
+		c.due.IsRecurring = true
+		next, err := nextDueDate(c.due, date(c.completed))
+		require.NoError(t, err, c.due.String)
+		require.Equal(t, c.expected, next, c.due.String)
+	}
+}
+
+func TestNextDueDateUnsupported(t *testing.T) {
+	_, err := nextDueDate(Due{String: "every day", Date: "2024-01-10"}, time.Now())
+	require.Error(t, err)
+
+	_, err = nextDueDate(Due{String: "hver dag", Date: "2024-01-10", Lang: "no", IsRecurring: true}, time.Now())
+	require.Error(t, err)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+)
+
+// A REST API on localhost, for editor plugins, status bars and scripts that want the tasks
+// without a token of their own:
+//
+//	GET   /tasks?filter=#Work      the tasks of the local db, like list --output json
+//	GET   /tasks/<id>              one task
+//	POST  /tasks                   {"content": "Buy milk tomorrow #Home"}, with quick add
+//	PATCH /tasks/<id>              {"content", "description", "priority": "p1", "labels", "due": "tomorrow"}
+//	POST  /tasks/<id>/close        complete the task, queued when offline
+//
+// Reads never hit the Todoist api, the local db is synced with the sync_interval of the config file.
+// Errors are {"error": "..."}.
+//
+// Every request needs "Authorization: Bearer <token>", with the token that is made for each run and
+// written to the token file. Requests from browsers, with an Origin header or another Host than
+// localhost, are rejected, so web pages can not use the api. POST and PATCH need a json Content-Type.
+
+type taskEdit struct {
+	Content     *string   `json:"content"`
+	Description *string   `json:"description"`
+	Priority    *string   `json:"priority"`
+	Labels      *[]string `json:"labels"`
+	Due         *string   `json:"due"`
+}
+
+func (c cli) serve(args []string) error {
+	fs := c.flagSet("serve")
+	addr := fs.String("addr", "127.0.0.1:7777", "Address to listen on.")
+	tokenFile := fs.String("token-file", "", "File the token is written to. (default serve.token in the cache dir)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("unexpected argument %q", fs.Arg(0))
+	}
+	interval, err := c.config.syncInterval()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if interval > 0 {
+		go c.syncEvery(ctx, interval)
+	}
+	if *tokenFile == "" {
+		cache, err := cacheDir()
+		if err != nil {
+			return err
+		}
+		*tokenFile = filepath.Join(cache, "serve.token")
+	}
+	token, err := newServeToken(*tokenFile)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*tokenFile)
+	server := &http.Server{Addr: *addr, Handler: localOnly(c.apiHandler(token))}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	fmt.Fprintf(c.stdout, "serving http://%s/tasks, token in %s\n", *addr, *tokenFile)
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Makes a random token and writes it to the file, readable only by the user
+func newServeToken(path string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	return token, os.WriteFile(path, []byte(token+"\n"), 0600)
+}
+
+// Rejects requests from browsers: with an Origin header, or a Host that is not localhost, like after DNS rebinding
+func localOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			apiError(w, http.StatusForbidden, errors.New("cross origin requests are not allowed"))
+			return
+		}
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			apiError(w, http.StatusForbidden, fmt.Errorf("host %q is not localhost", r.Host))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Checks the bearer token, and the json Content-Type of requests with a body
+func withToken(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			apiError(w, http.StatusUnauthorized, errors.New("missing or wrong token"))
+			return
+		}
+		if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				apiError(w, http.StatusUnsupportedMediaType, errors.New("Content-Type must be application/json"))
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (c cli) apiHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			c.apiList(w, r)
+		case http.MethodPost:
+			c.apiAdd(w, r)
+		default:
+			apiError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		}
+	})
+	mux.HandleFunc("/tasks/", func(w http.ResponseWriter, r *http.Request) {
+		id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/tasks/"), "/")
+		todos, err := c.storage.localTodos()
+		if err != nil {
+			apiError(w, http.StatusInternalServerError, err)
+			return
+		}
+		todo, err := findTodo(todos, id)
+		if err != nil || todo.Id != id {
+			apiError(w, http.StatusNotFound, fmt.Errorf("%w: %q", errNotFound, id))
+			return
+		}
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+			apiJSON(w, http.StatusOK, taskObject(todo, taskFields))
+		case action == "" && r.Method == http.MethodPatch:
+			c.apiEdit(w, r, todo)
+		case action == "close" && r.Method == http.MethodPost:
+			if _, err := c.storage.closeTask(todo); err != nil && !isRejected(err) {
+				apiError(w, http.StatusInternalServerError, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case action == "" || action == "close":
+			apiError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return withToken(token, mux)
+}
+
+func (c cli) apiList(w http.ResponseWriter, r *http.Request) {
+	todos, err := c.storage.localTodos()
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, err)
+		return
+	}
+	todos = filterContents(todos, r.URL.Query().Get("filter"))
+	sortTodos(todos, c.config.Sort)
+	list := make([]jsonObject, len(todos))
+	for i, t := range todos {
+		list[i] = taskObject(t, taskFields)
+	}
+	apiJSON(w, http.StatusOK, list)
+}
+
+func (c cli) apiAdd(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apiError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(body.Content) == "" {
+		apiError(w, http.StatusBadRequest, errors.New("missing content"))
+		return
+	}
+	id, todos, err := c.storage.quickAdd(body.Content)
+	if err != nil && id == "" {
+		apiError(w, http.StatusBadGateway, err)
+		return
+	}
+	if todo, err := findTodo(todos, id); err == nil && todo.Id == id {
+		apiJSON(w, http.StatusCreated, taskObject(todo, taskFields))
+		return
+	}
+	// Added, but the sync after it failed
+	apiJSON(w, http.StatusCreated, jsonObject{{name: "id", value: id}})
+}
+
+func (c cli) apiEdit(w http.ResponseWriter, r *http.Request, todo Todo) {
+	var edit taskEdit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		apiError(w, http.StatusBadRequest, err)
+		return
+	}
+	if edit.Content != nil {
+		todo.Content = *edit.Content
+	}
+	if edit.Description != nil {
+		todo.Description = *edit.Description
+	}
+	if edit.Priority != nil {
+		if !Contains([]string{"p1", "p2", "p3", "p4"}, *edit.Priority) {
+			apiError(w, http.StatusBadRequest, fmt.Errorf("priority %q is not p1 to p4", *edit.Priority))
+			return
+		}
+		todo.Priority = parsePriority(*edit.Priority)
+	}
+	if edit.Labels != nil {
+		todo.Labels = *edit.Labels
+	}
+	if edit.Due != nil {
+		todo.Due.ChangeString = *edit.Due
+	}
+	todos, err := c.storage.editTask(EditTaskData{todo: todo})
+	if err != nil && !isRejected(err) {
+		apiError(w, http.StatusBadGateway, err)
+		return
+	}
+	if updated, err := findTodo(todos, todo.Id); err == nil && updated.Id == todo.Id {
+		todo = updated
+	}
+	apiJSON(w, http.StatusOK, taskObject(todo, taskFields))
+}
+
+func apiJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, v)
+}
+
+func apiError(w http.ResponseWriter, status int, err error) {
+	apiJSON(w, status, jsonObject{{name: "error", value: err.Error()}})
+}
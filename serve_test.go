@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testServeToken = "secret"
+
+func newTestServer(t *testing.T) (cli, *httptest.Server) {
+	c, _, _ := newTestCLI(t)
+	server := httptest.NewServer(localOnly(c.apiHandler(testServeToken)))
+	t.Cleanup(server.Close)
+	return c, server
+}
+
+// Sends an authorized request, with a json body if one is given
+func apiRequest(t *testing.T, server *httptest.Server, method, path, body string) *http.Response {
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, server.URL+path, r)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testServeToken)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func TestServeAPI(t *testing.T) {
+	_, server := newTestServer(t)
+
+	res := apiRequest(t, server, http.MethodGet, "/tasks?filter=%23Work", "")
+	require.Equal(t, 200, res.StatusCode)
+	require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	var tasks []map[string]interface{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&tasks))
+	require.Equal(t, 1, len(tasks))
+	require.Equal(t, "Write report", tasks[0]["content"])
+	require.Equal(t, "Draft", tasks[0]["children"].([]interface{})[0].(map[string]interface{})["content"])
+
+	res = apiRequest(t, server, http.MethodGet, "/tasks/3", "")
+	var task map[string]interface{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&task))
+	require.Equal(t, "Draft", task["content"])
+
+	res = apiRequest(t, server, http.MethodGet, "/tasks/milk", "")
+	require.Equal(t, 404, res.StatusCode)
+
+	res = apiRequest(t, server, http.MethodPatch, "/tasks/1", `{"priority": "p9"}`)
+	require.Equal(t, 400, res.StatusCode)
+
+	res = apiRequest(t, server, http.MethodPost, "/tasks", `{"content": " "}`)
+	require.Equal(t, 400, res.StatusCode)
+}
+
+func TestServeAPIRejects(t *testing.T) {
+	_, server := newTestServer(t)
+	send := func(method, path string, header map[string]string) int {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(`{"content": "Hi"}`))
+		require.NoError(t, err)
+		for k, v := range header {
+			if k == "Host" {
+				req.Host = v
+				continue
+			}
+			req.Header.Set(k, v)
+		}
+		res, err := server.Client().Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	auth := "Bearer " + testServeToken
+
+	require.Equal(t, 401, send(http.MethodGet, "/tasks", nil))
+	require.Equal(t, 401, send(http.MethodGet, "/tasks", map[string]string{"Authorization": "Bearer wrong"}))
+	require.Equal(t, 403, send(http.MethodGet, "/tasks", map[string]string{"Authorization": auth, "Origin": "https://example.com"}))
+	require.Equal(t, 403, send(http.MethodGet, "/tasks", map[string]string{"Authorization": auth, "Host": "evil.example.com:7777"}))
+	require.Equal(t, 200, send(http.MethodGet, "/tasks", map[string]string{"Authorization": auth, "Host": "localhost:7777"}))
+	// Like a no-cors fetch from a web page
+	require.Equal(t, 415, send(http.MethodPost, "/tasks", map[string]string{"Authorization": auth, "Content-Type": "text/plain"}))
+	require.Equal(t, 415, send(http.MethodPost, "/tasks/1/close", map[string]string{"Authorization": auth}))
+	require.Equal(t, 415, send(http.MethodPatch, "/tasks/1", map[string]string{"Authorization": auth}))
+}
+
+func TestServeAPICloseOffline(t *testing.T) {
+	c, server := newTestServer(t)
+
+	res := apiRequest(t, server, http.MethodPost, "/tasks/1/close", "{}")
+	require.Equal(t, 204, res.StatusCode)
+
+	todos, err := c.storage.localTodos()
+	require.NoError(t, err)
+	_, err = findTodo(todos, "1")
+	require.ErrorIs(t, err, errNotFound)
+	queue, err := c.storage.db.getQueue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, len(queue))
+	require.Equal(t, "item_close", queue[0].Type)
+}
+
+func TestServeAPIEditRecurring(t *testing.T) {
+	c, server := newTestServer(t)
+	edits := fakeTodoist(t)
+	err := c.storage.db.InsertFromSync(context.Background(), SyncResponse{
+		SyncToken: "testing",
+		Items: []Item{{Id: "4", ProjectId: "11", Content: "Water plants", Priority: 1,
+			Due: Due{Date: "2024-01-05", String: "every friday", IsRecurring: true}}},
+	})
+	require.NoError(t, err)
+
+	res := apiRequest(t, server, http.MethodPatch, "/tasks/4", `{"priority": "p1", "labels": ["home"]}`)
+	require.Equal(t, 200, res.StatusCode)
+	require.Equal(t, 1, len(*edits))
+	require.Contains(t, (*edits)[0], `"priority":4`)
+	require.NotContains(t, (*edits)[0], `"due_`)
+
+	res = apiRequest(t, server, http.MethodPatch, "/tasks/4", `{"due": "every saturday"}`)
+	require.Equal(t, 200, res.StatusCode)
+	require.Contains(t, (*edits)[1], `"due_string":"every saturday"`)
+	require.NotContains(t, (*edits)[1], `"due_date"`)
+}
@@ -1,6 +1,6 @@
 package main
 
-import "time"
+import "sort"
 
 // Implements sort.Interface for []Todo based on priority
 
@@ -18,8 +18,9 @@ func (a ByDueThenPriority) Less(i, j int) bool {
 	if a[i].Due.Date == "" && a[j].Due.Date == "" {
 		return a[i].Priority > a[j].Priority
 	}
-	ti, erri := time.Parse("2006-01-02", a[i].Due.Date)
-	tj, errj := time.Parse("2006-01-02", a[j].Due.Date)
+	// Full-day tasks are sorted before tasks with a time on the same day
+	ti, _, erri := a[i].Due.Time()
+	tj, _, errj := a[j].Due.Time()
 	if erri != nil && errj != nil {
 		return a[i].Priority > a[j].Priority
 	}
@@ -34,3 +35,26 @@ func (a ByDueThenPriority) Less(i, j int) bool {
 	}
 	return ti.Before(tj)
 }
+
+type ByProject []Todo
+
+func (a ByProject) Len() int           { return len(a) }
+func (a ByProject) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByProject) Less(i, j int) bool { return a[i].ProjectName < a[j].ProjectName }
+
+const (
+	sortDue      = "due"
+	sortPriority = "priority"
+	sortProject  = "project"
+)
+
+// Sorts by due date, or by priority or project with the due date as tiebreaker
+func sortTodos(list []Todo, by string) {
+	sort.Stable(ByDueThenPriority(list))
+	switch by {
+	case sortPriority:
+		sort.Stable(ByPriority(list))
+	case sortProject:
+		sort.Stable(ByProject(list))
+	}
+}
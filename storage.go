@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -14,11 +16,14 @@ type Storage struct {
 type PendingResponse struct {
 	Projects []Project `json:"projects"`
 	Items    []Item    `json:"items"`
+	Sections []Section `json:"sections"`
 }
 
 type SyncResponse struct {
 	Projects  []Project `json:"projects"`
 	Items     []Item    `json:"items"`
+	Sections  []Section `json:"sections"`
+	Labels    []Label   `json:"labels"`
 	SyncToken string    `json:"sync_token"`
 }
 
@@ -32,6 +37,10 @@ func newContext() (context.Context, func()) {
 func (s Storage) fetchTodos() ([]Todo, error) {
 	ctx, cancel := newContext()
 	defer cancel()
+	rejected, err := s.flushQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
 	token, err := s.db.getToken(ctx)
 	if err != nil {
 		return nil, err
@@ -44,11 +53,62 @@ func (s Storage) fetchTodos() ([]Todo, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = s.reapplyQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
 	localRes, err := s.db.getPending(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return toTodos(localRes.Items, localRes.Projects), nil
+	return toTodos(localRes.Items, localRes.Projects), rejected
+}
+
+// Max page size of completed/get_all
+const completedPageSize = 200
+
+// Only the latest completed tasks are fetched, all of a large history would hit the timeout or the rate limit
+const completedMaxPages = 10
+
+// Fetches everything from scratch with the sync token "*", and replaces the local tables.
+// Queued commands are sent first, so they are not lost.
+func (s Storage) fullSync() (SyncReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	rejected, err := s.flushQueue(ctx)
+	if err != nil {
+		return SyncReport{}, err
+	}
+	res, err := s.api.getPending(ctx, "*")
+	if err != nil {
+		return SyncReport{}, err
+	}
+	completed := make([]CompletedItem, 0)
+	truncated := false
+	for i := 0; ; i++ {
+		if i == completedMaxPages {
+			truncated = true
+			break
+		}
+		page, err := s.api.getCompleted(ctx, completedPageSize, len(completed))
+		if err != nil {
+			return SyncReport{}, err
+		}
+		completed = append(completed, page...)
+		if len(page) < completedPageSize {
+			break
+		}
+	}
+	report, err := s.db.ReplaceFromSync(ctx, res, completed)
+	report.CompletedTruncated = truncated
+	if err != nil {
+		return report, err
+	}
+	err = s.reapplyQueue(ctx)
+	if err != nil {
+		return report, err
+	}
+	return report, rejected
 }
 
 func (s Storage) localTodos() ([]Todo, error) {
@@ -61,6 +121,45 @@ func (s Storage) localTodos() ([]Todo, error) {
 	return toTodos(res.Items, res.Projects), nil
 }
 
+func (s Storage) localSections() ([]Section, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	return s.db.getSections(ctx)
+}
+
+func (s Storage) localProjects() ([]Project, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	return s.db.getProjects(ctx)
+}
+
+func (s Storage) localCompleted() ([]CompletedItem, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	return s.db.getCompletedItems(ctx)
+}
+
+func (s Storage) localLabels() ([]Label, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	return s.db.getLabels(ctx)
+}
+
+// Moves the task to a section. An empty sectionId moves it out of any section, in the same project.
+func (s Storage) moveToSection(todo Todo, sectionId string) ([]Todo, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	args := map[string]interface{}{"section_id": sectionId}
+	if sectionId == "" {
+		args = map[string]interface{}{"project_id": todo.ProjectId}
+	}
+	err := s.api.moveTask(ctx, todo.Id, args)
+	if err != nil {
+		return nil, err
+	}
+	return s.fetchTodos()
+}
+
 func (s Storage) newTask(todo Todo) ([]Todo, error) {
 	ctx, cancel := newContext()
 	defer cancel()
@@ -74,7 +173,7 @@ func (s Storage) newTask(todo Todo) ([]Todo, error) {
 func (s Storage) editTask(data EditTaskData) ([]Todo, error) {
 	ctx, cancel := newContext()
 	defer cancel()
-	err := s.api.editTask(ctx, data.todo)
+	err := s.api.editTask(ctx, data.todo, s.localDue(ctx, data.todo.Id))
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +181,7 @@ func (s Storage) editTask(data EditTaskData) ([]Todo, error) {
 	for _, child := range data.updateChildren {
 		switch child.UpdateStatus {
 		case UpdateStatusModified:
-			err = s.api.editTask(ctx, child.Org)
+			err = s.api.editTask(ctx, child.Org, child.Org.Due)
 			if child.Checked {
 				err = s.api.markAsDone(ctx, child.Org)
 			}
@@ -96,17 +195,36 @@ func (s Storage) editTask(data EditTaskData) ([]Todo, error) {
 	return s.fetchTodos()
 }
 
-func (s Storage) quickAdd(content string) ([]Todo, error) {
+// The due of the task in the local db, to tell if an edit changed it
+func (s Storage) localDue(ctx context.Context, id string) Due {
+	items, err := s.db.getPendingItems(ctx)
+	if err != nil {
+		return Due{}
+	}
+	for _, item := range items {
+		if item.Id == id {
+			return item.Due
+		}
+	}
+	return Due{}
+}
+
+// Returns the id of the new task
+func (s Storage) quickAdd(content string) (string, []Todo, error) {
 	ctx, cancel := newContext()
 	defer cancel()
-	_, err := s.api.quickAdd(ctx, content)
+	id, err := s.api.quickAdd(ctx, content)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
-	return s.fetchTodos()
+	todos, err := s.fetchTodos()
+	return id, todos, err
 }
 
 func (s Storage) markAsDone(todo Todo) ([]Todo, error) {
+	if todo.Due.IsRecurring {
+		return s.completeRecurring(todo)
+	}
 	ctx, cancel := newContext()
 	defer cancel()
 	err := s.api.markAsDone(ctx, todo)
@@ -115,3 +233,181 @@ func (s Storage) markAsDone(todo Todo) ([]Todo, error) {
 	}
 	return s.fetchTodos()
 }
+
+// Rolls the due date of a recurring task locally and queues the close for the server.
+// If we are offline the task still shows up with its next date, and the close is
+// sent on the next sync. The server result replaces our local date when it comes back.
+func (s Storage) completeRecurring(todo Todo) ([]Todo, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+	next, err := nextDueDate(todo.Due, time.Now())
+	if err != nil {
+		next = "" // Not something we can evaluate, wait for the server to roll it.
+	}
+	cmd := newSyncCommand("item_close", map[string]interface{}{
+		"id": todo.Id,
+	})
+	err = s.db.enqueue(ctx, cmd, next)
+	if err != nil {
+		return nil, err
+	}
+	if next != "" {
+		err = s.db.setDueDate(ctx, todo.Id, next)
+		if err != nil {
+			return nil, err
+		}
+	}
+	todos, err := s.fetchTodos()
+	if err != nil && next != "" && !isRejected(err) {
+		// Probably offline. Show what we have locally, the queue is flushed on the next sync.
+		return s.localTodos()
+	}
+	return todos, err
+}
+
+// Like markAsDone, but the close is queued and the task checked locally, so it works offline.
+func (s Storage) closeTask(todo Todo) ([]Todo, error) {
+	if todo.Due.IsRecurring {
+		return s.completeRecurring(todo)
+	}
+	ctx, cancel := newContext()
+	defer cancel()
+	cmd := newSyncCommand("item_close", map[string]interface{}{
+		"id": todo.Id,
+	})
+	err := s.db.enqueue(ctx, cmd, "")
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.setChecked(ctx, todo.Id)
+	if err != nil {
+		return nil, err
+	}
+	todos, err := s.fetchTodos()
+	if err != nil && !isRejected(err) {
+		return s.localTodos()
+	}
+	return todos, err
+}
+
+// Max number of commands in one sync request
+const syncBatchSize = 100
+
+// Sends the commands in batches, and returns the ids of the temp ids.
+// Temp ids used by later batches are replaced with the real ids, since they are only known within a request.
+func (s Storage) sendCommands(commands []SyncCommand) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	ids := make(map[string]string)
+	for start := 0; start < len(commands); start += syncBatchSize {
+		end := start + syncBatchSize
+		if end > len(commands) {
+			end = len(commands)
+		}
+		batch := commands[start:end]
+		for _, cmd := range batch {
+			for k, v := range cmd.Args {
+				if id, ok := v.(string); ok && ids[id] != "" {
+					cmd.Args[k] = ids[id]
+				}
+			}
+		}
+		res, err := s.api.sync(ctx, batch)
+		if err != nil {
+			return ids, err
+		}
+		for tempId, id := range res.TempIdMapping {
+			ids[tempId] = id
+		}
+		for _, cmd := range batch {
+			if err := res.err(cmd); err != nil {
+				return ids, err
+			}
+		}
+	}
+	return ids, nil
+}
+
+// The queued commands the server rejected. The sync goes on without them, and the
+// tasks it fetched are returned with this error.
+type rejectedError struct {
+	errs []error
+}
+
+func (e rejectedError) Error() string {
+	s := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		s[i] = err.Error()
+	}
+	return "rejected by the server: " + strings.Join(s, "; ")
+}
+
+func isRejected(err error) bool {
+	var rejected rejectedError
+	return errors.As(err, &rejected)
+}
+
+// Sends queued commands to the server.
+// Commands the server rejected are dropped, since retrying them wont help,
+// and returned as the rejectedError, so the sync can go on.
+func (s Storage) flushQueue(ctx context.Context) (rejected error, err error) {
+	queued, err := s.db.getQueue(ctx)
+	if err != nil || len(queued) == 0 {
+		return nil, err
+	}
+	commands := make([]SyncCommand, 0, len(queued))
+	for _, q := range queued {
+		commands = append(commands, q.SyncCommand)
+	}
+	res, err := s.api.sync(ctx, commands)
+	if err != nil {
+		return nil, err
+	}
+	errs, err := s.dequeueSynced(ctx, commands, res)
+	if err != nil || len(errs) == 0 {
+		return nil, err
+	}
+	return rejectedError{errs: errs}, nil
+}
+
+// Removes the commands with a sync status from the queue, and returns the errors of the rejected ones
+func (s Storage) dequeueSynced(ctx context.Context, commands []SyncCommand, res SyncCommandResponse) ([]error, error) {
+	var errs []error
+	for _, cmd := range commands {
+		if _, ok := res.SyncStatus[cmd.Uuid]; !ok {
+			continue
+		}
+		if e := res.err(cmd); e != nil {
+			errs = append(errs, e)
+		}
+		err := s.db.dequeue(ctx, cmd.Uuid)
+		if err != nil {
+			return errs, err
+		}
+	}
+	return errs, nil
+}
+
+// Items synced from the server override our local changes,
+// so commands that are still queued have to be applied again.
+func (s Storage) reapplyQueue(ctx context.Context) error {
+	queued, err := s.db.getQueue(ctx)
+	if err != nil {
+		return err
+	}
+	for _, q := range queued {
+		id, _ := q.Args["id"].(string)
+		if q.Type != "item_close" || id == "" {
+			continue
+		}
+		if q.LocalDue != "" {
+			err = s.db.setDueDate(ctx, id, q.LocalDue)
+		} else {
+			err = s.db.setChecked(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Taskwarrior, the json of `task export` and `task import`.
+//
+// Priorities p1 to p3 are H, M and L. Sections are subprojects, "Project.Section".
+// Subtasks are tasks the parent depends on. The description is an annotation.
+// The Todoist id and recurring due string are kept in the todoist_id and todoist_due
+// attributes, so importing a file exported from todui updates the tasks instead of adding them.
+// Completed tasks are exported with a todoist_completed id, and are not imported again.
+
+const taskwarriorTimeLayout = "20060102T150405Z"
+
+// Tasks get the same uuid in every export
+var taskwarriorNamespace = uuid.MustParse("6f0d7a3c-2f4e-4b8e-9d55-6a1c7e0b9f21")
+
+type taskwarriorTask struct {
+	Uuid        string                  `json:"uuid"`
+	Description string                  `json:"description"`
+	Status      string                  `json:"status"`
+	Project     string                  `json:"project,omitempty"`
+	Tags        []string                `json:"tags,omitempty"`
+	Priority    string                  `json:"priority,omitempty"`
+	Due         string                  `json:"due,omitempty"`
+	End         string                  `json:"end,omitempty"`
+	Depends     taskwarriorDepends      `json:"depends,omitempty"`
+	Annotations []taskwarriorAnnotation `json:"annotations,omitempty"`
+	TodoistId   string                  `json:"todoist_id,omitempty"`
+	TodoistDue  string                  `json:"todoist_due,omitempty"`
+	// The id of a completed task, these are not imported again
+	TodoistCompleted string `json:"todoist_completed,omitempty"`
+
+	// Only read to tell what was not imported
+	Recur     string `json:"recur,omitempty"`
+	Wait      string `json:"wait,omitempty"`
+	Scheduled string `json:"scheduled,omitempty"`
+}
+
+type taskwarriorAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// An array, or the comma separated string of older versions
+type taskwarriorDepends []string
+
+func (d *taskwarriorDepends) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*d = strings.FieldsFunc(s, func(r rune) bool { return r == ',' })
+		return nil
+	}
+	var list []string
+	err := json.Unmarshal(b, &list)
+	*d = list
+	return err
+}
+
+func taskwarriorUuid(id string) string {
+	return uuid.NewSHA1(taskwarriorNamespace, []byte(id)).String()
+}
+
+func exportTaskwarrior(w io.Writer, data exportData) error {
+	now := time.Now().UTC().Format(taskwarriorTimeLayout)
+	tasks := make([]taskwarriorTask, 0)
+	var add func(t Todo, project string)
+	add = func(t Todo, project string) {
+		task := taskwarriorTask{
+			Uuid:        taskwarriorUuid(t.Id),
+			Description: t.Content,
+			Status:      "pending",
+			Project:     project,
+			Tags:        t.Labels,
+			Priority:    taskwarriorPriority(t.Priority),
+			Due:         taskwarriorDue(t.Due),
+			TodoistId:   t.Id,
+		}
+		if t.Due.IsRecurring {
+			task.TodoistDue = t.Due.String
+		}
+		if t.Description != "" {
+			task.Annotations = []taskwarriorAnnotation{{Entry: now, Description: t.Description}}
+		}
+		for _, c := range t.Children {
+			task.Depends = append(task.Depends, taskwarriorUuid(c.Id))
+		}
+		tasks = append(tasks, task)
+		for _, c := range t.Children {
+			add(c, project)
+		}
+	}
+	for _, t := range data.todos {
+		project := t.ProjectName
+		if section := data.sectionName(t.SectionId); section != "" {
+			project += "." + section
+		}
+		add(t, project)
+	}
+	for _, t := range data.completed {
+		task := taskwarriorTask{
+			Uuid:             taskwarriorUuid("completed/" + t.Id),
+			Description:      t.Content,
+			Status:           "completed",
+			Project:          t.ProjectName,
+			TodoistCompleted: t.Id,
+		}
+		if end, err := time.Parse(time.RFC3339, t.CompletedAt); err == nil {
+			task.End = end.UTC().Format(taskwarriorTimeLayout)
+		}
+		tasks = append(tasks, task)
+	}
+	return writeJSON(w, tasks)
+}
+
+func taskwarriorPriority(p int) string {
+	switch p {
+	case 4:
+		return "H"
+	case 3:
+		return "M"
+	case 2:
+		return "L"
+	}
+	return ""
+}
+
+// Full-day tasks are due at the start of the day
+func taskwarriorDue(due Due) string {
+	t, _, err := due.Time()
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(taskwarriorTimeLayout)
+}
+
+// Due dates at midnight are full-day
+func parseTaskwarriorDue(s string) (string, error) {
+	t, err := time.Parse(taskwarriorTimeLayout, s)
+	if err != nil {
+		return "", err
+	}
+	t = t.Local()
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format(dueDateLayout), nil
+	}
+	return t.Format(dueFloatingLayout), nil
+}
+
+func parseTaskwarrior(r io.Reader, data exportData) ([]importTask, error) {
+	var tasks []taskwarriorTask
+	err := json.NewDecoder(r).Decode(&tasks)
+	if err != nil {
+		return nil, fmt.Errorf("not a taskwarrior export: %w", err)
+	}
+	existing := make(map[string]bool)
+	var walk func(todos []Todo)
+	walk = func(todos []Todo) {
+		for _, t := range todos {
+			existing[t.Id] = true
+			walk(t.Children)
+		}
+	}
+	walk(data.todos)
+
+	// A task depended on by exactly one other task becomes its subtask
+	dependents := make(map[string][]string)
+	byUuid := make(map[string]taskwarriorTask)
+	for _, t := range tasks {
+		byUuid[t.Uuid] = t
+		for _, d := range t.Depends {
+			dependents[d] = append(dependents[d], t.Uuid)
+		}
+	}
+	parent := make(map[string]string)
+	for child, parents := range dependents {
+		if len(parents) == 1 && !dependsOn(byUuid, child, parents[0]) {
+			parent[child] = parents[0]
+		}
+	}
+
+	imported := make(map[string]*importTask)
+	order := make([]string, 0)
+	for _, tw := range tasks {
+		if tw.Status == "deleted" || tw.Status == "recurring" || tw.TodoistCompleted != "" {
+			continue
+		}
+		t := importTask{
+			ref:         "taskwarrior " + tw.Uuid,
+			content:     tw.Description,
+			labels:      tw.Tags,
+			priority:    1,
+			checked:     tw.Status == "completed",
+			description: taskwarriorDescription(tw.Annotations),
+		}
+		if existing[tw.TodoistId] {
+			t.id = tw.TodoistId
+		}
+		t.project, t.section, _ = strings.Cut(tw.Project, ".")
+		switch tw.Priority {
+		case "H":
+			t.priority = 4
+		case "M":
+			t.priority = 3
+		case "L":
+			t.priority = 2
+		}
+		if tw.Due != "" {
+			t.due.Date, err = parseTaskwarriorDue(tw.Due)
+			if err != nil {
+				t.notes = append(t.notes, "invalid due "+tw.Due)
+			}
+		}
+		if tw.TodoistDue != "" {
+			t.due = Due{Date: t.due.Date, String: tw.TodoistDue, IsRecurring: true}
+		}
+		if tw.Recur != "" {
+			t.notes = append(t.notes, "recur "+tw.Recur+" not imported, only the due date")
+		}
+		if tw.Wait != "" || tw.Scheduled != "" {
+			t.notes = append(t.notes, "wait and scheduled not imported")
+		}
+		for _, d := range tw.Depends {
+			if parent[d] != tw.Uuid {
+				t.notes = append(t.notes, "dependency on "+d+" not imported")
+			}
+		}
+		imported[tw.Uuid] = &t
+		order = append(order, tw.Uuid)
+	}
+
+	// Children are added to their parents, deepest first, since the tasks are copied
+	sort.SliceStable(order, func(i, j int) bool {
+		return depth(parent, order[i]) > depth(parent, order[j])
+	})
+	roots := make(map[string]bool)
+	for _, id := range order {
+		t := imported[id]
+		p, ok := imported[parent[id]]
+		if !ok {
+			roots[id] = true
+			continue
+		}
+		p.children = append(p.children, *t)
+	}
+	res := make([]importTask, 0)
+	for _, tw := range tasks {
+		if roots[tw.Uuid] {
+			res = append(res, *imported[tw.Uuid])
+		}
+	}
+	return res, nil
+}
+
+// Whether a depends on b, directly or not
+func dependsOn(tasks map[string]taskwarriorTask, a, b string) bool {
+	seen := make(map[string]bool)
+	var walk func(id string) bool
+	walk = func(id string) bool {
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+		for _, d := range tasks[id].Depends {
+			if d == b || walk(d) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(a)
+}
+
+func depth(parent map[string]string, id string) int {
+	d := 0
+	for p, ok := parent[id]; ok && d < len(parent); p, ok = parent[p] {
+		d++
+	}
+	return d
+}
+
+func taskwarriorDescription(annotations []taskwarriorAnnotation) string {
+	lines := make([]string, len(annotations))
+	for i, a := range annotations {
+		lines[i] = a.Description
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskwarriorRoundTrip(t *testing.T) {
+	data := exportData{
+		projects: []Project{{Id: "11", Name: "Work"}},
+		sections: []Section{{Id: "21", ProjectId: "11", Name: "Q1"}},
+		todos: []Todo{{
+			Id:          "1",
+			ProjectName: "Work",
+			SectionId:   "21",
+			Content:     "Write report",
+			Description: "for monday",
+			Priority:    3,
+			Labels:      []string{"office"},
+			Due:         Due{Date: "2024-01-01", String: "every monday", IsRecurring: true},
+			Children:    []Todo{{Id: "2", ProjectName: "Work", Content: "Draft", Priority: 1, Due: Due{Date: "2024-01-01T10:30:00"}}},
+		}},
+		completed: []completedTask{{Todo: Todo{Id: "9", ProjectName: "Work", Content: "Old report"}, CompletedAt: "2023-12-01T10:00:00Z"}},
+	}
+	var b bytes.Buffer
+	require.NoError(t, exportTaskwarrior(&b, data))
+
+	var exported []map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &exported))
+	require.Equal(t, 3, len(exported))
+	require.Equal(t, "completed", exported[2]["status"])
+	require.Equal(t, "9", exported[2]["todoist_completed"])
+	require.Equal(t, "Work.Q1", exported[0]["project"])
+	require.Equal(t, "M", exported[0]["priority"])
+	require.Equal(t, []interface{}{exported[1]["uuid"]}, exported[0]["depends"])
+	require.Equal(t, taskwarriorUuid("1"), exported[0]["uuid"])
+
+	tasks, err := parseTaskwarrior(&b, data)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(tasks))
+	require.Equal(t, "1", tasks[0].id)
+	require.Equal(t, "Write report", tasks[0].content)
+	require.Equal(t, "for monday", tasks[0].description)
+	require.Equal(t, "Work", tasks[0].project)
+	require.Equal(t, "Q1", tasks[0].section)
+	require.Equal(t, 3, tasks[0].priority)
+	require.Equal(t, []string{"office"}, tasks[0].labels)
+	require.Equal(t, Due{Date: "2024-01-01", String: "every monday", IsRecurring: true}, tasks[0].due)
+	require.Equal(t, 1, len(tasks[0].children))
+	require.Equal(t, "2", tasks[0].children[0].id)
+	require.Equal(t, "2024-01-01T10:30:00", tasks[0].children[0].due.Date)
+}
+
+func TestParseTaskwarrior(t *testing.T) {
+	tasks, err := parseTaskwarrior(strings.NewReader(`[
+{"uuid":"a","description":"Plan trip","status":"pending","project":"Home","tags":["travel"],"priority":"H","depends":"b,c"},
+{"uuid":"b","description":"Book hotel","status":"completed","end":"20240101T100000Z"},
+{"uuid":"c","description":"Pack","status":"pending","recur":"weekly","due":"20240105T120000Z"},
+{"uuid":"d","description":"Also needs packing","status":"pending","depends":["c"]},
+{"uuid":"e","description":"Gone","status":"deleted"},
+{"uuid":"f","description":"Template","status":"recurring"}
+]`), exportData{})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(tasks))
+
+	require.Equal(t, "Plan trip", tasks[0].content)
+	require.Equal(t, "", tasks[0].id)
+	require.Equal(t, 4, tasks[0].priority)
+	require.Equal(t, []string{"travel"}, tasks[0].labels)
+	require.Equal(t, 1, len(tasks[0].children))
+	require.Equal(t, "Book hotel", tasks[0].children[0].content)
+	require.True(t, tasks[0].children[0].checked)
+	// Depended on by two tasks, so not a subtask
+	require.Equal(t, []string{"dependency on c not imported"}, tasks[0].notes)
+	require.Equal(t, "Pack", tasks[1].content)
+	require.Equal(t, 1, len(tasks[1].notes))
+	require.Equal(t, "Also needs packing", tasks[2].content)
+
+	_, err = parseTaskwarrior(strings.NewReader(`{"not": "a list"}`), exportData{})
+	require.Error(t, err)
+}
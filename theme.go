@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// All colors come from the theme. A theme is picked by name in the config file,
+// either one of the bundled themes or one defined in the config:
+//
+//	theme = "mine"
+//	project_colors = true
+//
+//	[themes.mine]
+//	base = "light"
+//	due = "#8839ef"
+//
+// Colors are ANSI numbers or hex values. Colors that are not set are taken from the base theme.
+// An empty color means no color, which is what all colors are when NO_COLOR is set.
+
+type Theme struct {
+	Base      string `toml:"base"`
+	Text      string `toml:"text"`
+	Dim       string `toml:"dim"`
+	Chosen    string `toml:"chosen"`
+	Project   string `toml:"project"`
+	Due       string `toml:"due"`
+	Overdue   string `toml:"overdue"`
+	Labels    string `toml:"labels"`
+	P1        string `toml:"p1"`
+	P2        string `toml:"p2"`
+	P3        string `toml:"p3"`
+	Selection string `toml:"selection"` // background of the selected day
+	Border    string `toml:"border"`
+	Focused   string `toml:"focused"` // border of the focused column
+	Input     string `toml:"input"`
+	Help      string `toml:"help"`
+	Error     string `toml:"error"`
+}
+
+const defaultTheme = "dark"
+
+var themes = map[string]Theme{
+	"dark": {
+		Text:      "15",
+		Dim:       "8",
+		Chosen:    "3",
+		Project:   "14",
+		Due:       "5",
+		Overdue:   "1",
+		Labels:    "5",
+		P1:        "1",
+		P2:        "3",
+		P3:        "4",
+		Selection: "8",
+		Border:    "8",
+		Focused:   "3",
+		Input:     "3",
+		Help:      "12",
+		Error:     "1",
+	},
+	"light": {
+		Text:      "0",
+		Dim:       "245",
+		Chosen:    "130",
+		Project:   "30",
+		Due:       "90",
+		Overdue:   "160",
+		Labels:    "90",
+		P1:        "160",
+		P2:        "130",
+		P3:        "25",
+		Selection: "252",
+		Border:    "250",
+		Focused:   "130",
+		Input:     "130",
+		Help:      "25",
+		Error:     "160",
+	},
+	"high-contrast": {
+		Text:      "15",
+		Dim:       "7",
+		Chosen:    "11",
+		Project:   "14",
+		Due:       "13",
+		Overdue:   "9",
+		Labels:    "13",
+		P1:        "9",
+		P2:        "11",
+		P3:        "12",
+		Selection: "4",
+		Border:    "15",
+		Focused:   "11",
+		Input:     "11",
+		Help:      "15",
+		Error:     "9",
+	},
+}
+
+// Todoist color names, as used by projects and labels
+var todoistColors = map[string]string{
+	"berry_red":   "#b8256f",
+	"red":         "#db4035",
+	"orange":      "#ff9933",
+	"yellow":      "#fad000",
+	"olive_green": "#afb83b",
+	"lime_green":  "#7ecc49",
+	"green":       "#299438",
+	"mint_green":  "#6accbc",
+	"teal":        "#158fad",
+	"sky_blue":    "#14aaf5",
+	"light_blue":  "#96c3eb",
+	"blue":        "#4073ff",
+	"grape":       "#884dff",
+	"violet":      "#af38eb",
+	"lavender":    "#eb96eb",
+	"magenta":     "#e05194",
+	"salmon":      "#ff8d85",
+	"charcoal":    "#808080",
+	"grey":        "#b8b8b8",
+	"taupe":       "#ccac93",
+}
+
+// The styles are set by applyTheme
+var (
+	defaultTextStyle        lipgloss.Style
+	dimTextStyle            lipgloss.Style
+	chosenTextStyle         lipgloss.Style
+	projectStyle            lipgloss.Style
+	dueDateStyle            lipgloss.Style
+	dueDateOverdueStyle     lipgloss.Style
+	labelsStyle             lipgloss.Style
+	p1Style                 lipgloss.Style
+	p2Style                 lipgloss.Style
+	p3Style                 lipgloss.Style
+	inputTextStyle          lipgloss.Style
+	helpTextStyle           lipgloss.Style
+	errorTextStyle          lipgloss.Style
+	borderStyle             lipgloss.Style
+	calendarSelectedStyle   lipgloss.Style
+	calendarTodayStyle      lipgloss.Style
+	boardColumnStyle        lipgloss.Style
+	boardFocusedColumnStyle lipgloss.Style
+
+	// Use the Todoist colors of projects and labels
+	useTodoistColors bool
+	// Todoist color of each label, by name
+	labelColors = map[string]string{}
+)
+
+func init() {
+	applyTheme(themes[defaultTheme], false)
+}
+
+// Foreground style, without color if c is empty
+func foreground(c string) lipgloss.Style {
+	if c == "" {
+		return lipgloss.NewStyle()
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(c))
+}
+
+func applyTheme(t Theme, todoistColors bool) {
+	defaultTextStyle = foreground(t.Text)
+	dimTextStyle = foreground(t.Dim)
+	chosenTextStyle = foreground(t.Chosen)
+	projectStyle = foreground(t.Project)
+	dueDateStyle = foreground(t.Due)
+	dueDateOverdueStyle = foreground(t.Overdue)
+	labelsStyle = foreground(t.Labels)
+	p1Style = foreground(t.P1)
+	p2Style = foreground(t.P2)
+	p3Style = foreground(t.P3)
+	inputTextStyle = foreground(t.Input)
+	helpTextStyle = foreground(t.Help)
+	errorTextStyle = foreground(t.Error)
+	borderStyle = foreground(t.Border)
+
+	if t.Selection == "" {
+		calendarSelectedStyle = lipgloss.NewStyle().Reverse(true)
+	} else {
+		calendarSelectedStyle = lipgloss.NewStyle().Background(lipgloss.Color(t.Selection))
+	}
+	calendarTodayStyle = foreground(t.Chosen).Bold(true)
+
+	boardColumnStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		Padding(0, 1)
+	boardFocusedColumnStyle = boardColumnStyle.Copy()
+	if t.Border != "" {
+		boardColumnStyle = boardColumnStyle.BorderForeground(lipgloss.Color(t.Border))
+	}
+	if t.Focused != "" {
+		boardFocusedColumnStyle = boardFocusedColumnStyle.BorderForeground(lipgloss.Color(t.Focused))
+	}
+
+	useTodoistColors = todoistColors
+}
+
+// Finds the theme by name, with the themes from the config taking precedence over the bundled ones.
+// A theme from the config with the same name as a bundled theme is based on it by default.
+func resolveTheme(name string, custom map[string]Theme) (Theme, error) {
+	if name == "" {
+		name = defaultTheme
+	}
+	chain := make([]Theme, 0)
+	for {
+		t, ok := custom[name]
+		if !ok {
+			break
+		}
+		if len(chain) > len(custom) {
+			return Theme{}, fmt.Errorf("theme %q: base themes form a loop", name)
+		}
+		chain = append(chain, t)
+		base := t.Base
+		if base == "" {
+			base = defaultTheme
+		}
+		if base == name {
+			break
+		}
+		name = base
+	}
+	resolved, ok := themes[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q", name)
+	}
+	// Colors that are set replace the colors of the base
+	r := reflect.ValueOf(&resolved).Elem()
+	for i := len(chain) - 1; i >= 0; i-- {
+		v := reflect.ValueOf(chain[i])
+		for j := 0; j < v.NumField(); j++ {
+			if v.Field(j).String() != "" {
+				r.Field(j).SetString(v.Field(j).String())
+			}
+		}
+	}
+	resolved.Base = ""
+	return resolved, nil
+}
+
+// Applies the theme from the config. NO_COLOR disables all colors.
+func setupTheme(config Config) error {
+	if os.Getenv("NO_COLOR") != "" {
+		applyTheme(Theme{}, false)
+		return nil
+	}
+	t, err := resolveTheme(config.Theme, config.Themes)
+	if err != nil {
+		return err
+	}
+	applyTheme(t, config.ProjectColors)
+	return nil
+}
+
+func setLabelColors(labels []Label) {
+	colors := make(map[string]string, len(labels))
+	for _, l := range labels {
+		colors[l.Name] = l.Color
+	}
+	labelColors = colors
+}
+
+// Style of a project with the given Todoist color
+func projectColorStyle(color string) lipgloss.Style {
+	if hex, ok := todoistColors[color]; ok && useTodoistColors {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(hex))
+	}
+	return projectStyle.Copy()
+}
+
+func labelStyle(label string) lipgloss.Style {
+	if hex, ok := todoistColors[labelColors[label]]; ok && useTodoistColors {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(hex))
+	}
+	return labelsStyle
+}
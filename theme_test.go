@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveTheme(t *testing.T) {
+	theme, err := resolveTheme("light", nil)
+	require.NoError(t, err)
+	require.Equal(t, themes["light"], theme)
+
+	custom := map[string]Theme{
+		"mine":  {Base: "light", Due: "#8839ef"},
+		"other": {Base: "mine", Overdue: "9"},
+		"dark":  {Text: "7"},
+		"loop":  {Base: "loop2"},
+		"loop2": {Base: "loop"},
+	}
+	theme, err = resolveTheme("other", custom)
+	require.NoError(t, err)
+	require.Equal(t, "#8839ef", theme.Due)
+	require.Equal(t, "9", theme.Overdue)
+	require.Equal(t, themes["light"].Text, theme.Text)
+	require.Equal(t, "", theme.Base)
+
+	// Overrides the bundled theme
+	theme, err = resolveTheme("dark", custom)
+	require.NoError(t, err)
+	require.Equal(t, "7", theme.Text)
+	require.Equal(t, themes["dark"].Due, theme.Due)
+
+	_, err = resolveTheme("nope", custom)
+	require.Error(t, err)
+	_, err = resolveTheme("loop", custom)
+	require.Error(t, err)
+}
+
+func TestSetupTheme(t *testing.T) {
+	defer applyTheme(themes[defaultTheme], false)
+
+	t.Setenv("NO_COLOR", "1")
+	require.NoError(t, setupTheme(Config{Theme: "light", ProjectColors: true}))
+	require.Equal(t, lipgloss.NoColor{}, dueDateStyle.GetForeground())
+	require.Equal(t, lipgloss.NoColor{}, projectColorStyle("red").GetForeground())
+
+	t.Setenv("NO_COLOR", "")
+	require.NoError(t, setupTheme(Config{Theme: "light", ProjectColors: true}))
+	require.Equal(t, lipgloss.Color("90"), dueDateStyle.GetForeground())
+	require.Equal(t, lipgloss.Color("#db4035"), projectColorStyle("red").GetForeground())
+	require.Equal(t, lipgloss.Color("30"), projectColorStyle("").GetForeground())
+
+	setLabelColors([]Label{{Name: "urgent", Color: "red"}})
+	require.Equal(t, lipgloss.Color("#db4035"), labelStyle("urgent").GetForeground())
+	require.Equal(t, lipgloss.Color("90"), labelStyle("home").GetForeground())
+
+	require.Error(t, setupTheme(Config{Theme: "nope"}))
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// todo.txt, see https://github.com/todotxt/todo.txt
+//
+//	(A) Call mom +Family @phone due:2024-01-31
+//	x 2024-01-02 Buy milk +Home
+//
+// p1 to p3 are the priorities (A) to (C), projects and labels are +project and @label,
+// with the spaces in names replaced by _. Subtasks are written as tasks of their own,
+// and descriptions are left out since a task is a single line.
+
+const todoTxtDate = "2006-01-02"
+
+func exportTodoTxt(w io.Writer, data exportData) error {
+	bw := bufio.NewWriter(w)
+	var write func(todos []Todo)
+	write = func(todos []Todo) {
+		for _, t := range todos {
+			fmt.Fprintln(bw, todoTxtLine(t, ""))
+			write(t.Children)
+		}
+	}
+	write(data.todos)
+	for _, t := range data.completed {
+		date := t.CompletedAt
+		if len(date) > len(todoTxtDate) {
+			date = date[:len(todoTxtDate)]
+		}
+		fmt.Fprintln(bw, todoTxtLine(t.Todo, date))
+	}
+	return bw.Flush()
+}
+
+func todoTxtLine(t Todo, completedAt string) string {
+	words := make([]string, 0)
+	if t.Checked {
+		words = append(words, "x")
+		if completedAt != "" {
+			words = append(words, completedAt)
+		}
+	} else if p := todoTxtPriority(t.Priority); p != "" {
+		words = append(words, "("+p+")")
+	}
+	words = append(words, strings.Fields(t.Content)...)
+	if t.ProjectName != "" {
+		words = append(words, "+"+todoTxtName(t.ProjectName))
+	}
+	for _, l := range t.Labels {
+		words = append(words, "@"+todoTxtName(l))
+	}
+	if len(t.Due.Date) >= len(todoTxtDate) {
+		words = append(words, "due:"+t.Due.Date[:len(todoTxtDate)])
+	}
+	if t.Checked {
+		if p := todoTxtPriority(t.Priority); p != "" {
+			words = append(words, "pri:"+p)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func todoTxtPriority(p int) string {
+	switch p {
+	case 4:
+		return "A"
+	case 3:
+		return "B"
+	case 2:
+		return "C"
+	}
+	return ""
+}
+
+func todoTxtName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+func parseTodoTxt(r io.Reader, data exportData) ([]importTask, error) {
+	tasks := make([]importTask, 0)
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		tasks = append(tasks, parseTodoTxtLine(line, fmt.Sprintf("line %d", n), data))
+	}
+	return tasks, scanner.Err()
+}
+
+func parseTodoTxtLine(line, ref string, data exportData) importTask {
+	t := importTask{ref: ref, priority: 1}
+	words := strings.Fields(line)
+	if len(words) > 0 && words[0] == "x" {
+		t.checked = true
+		words = words[1:]
+	} else if len(words) > 0 && len(words[0]) == 3 && words[0][0] == '(' && words[0][2] == ')' {
+		t.priority = t.parsePriority(words[0][1:2])
+		words = words[1:]
+	}
+	// Completion and creation dates
+	for i := 0; i < 2 && len(words) > 0; i++ {
+		if _, err := time.Parse(todoTxtDate, words[0]); err != nil {
+			break
+		}
+		words = words[1:]
+	}
+	content := make([]string, 0, len(words))
+	for _, w := range words {
+		switch {
+		case len(w) > 1 && w[0] == '+' && t.project == "":
+			t.project = todoTxtProject(w[1:], data.projects)
+		case len(w) > 1 && w[0] == '@':
+			t.labels = append(t.labels, todoTxtLabel(w[1:], data.todos))
+		case strings.HasPrefix(w, "due:"):
+			date := strings.TrimPrefix(w, "due:")
+			if _, err := time.Parse(todoTxtDate, date); err != nil {
+				t.notes = append(t.notes, "invalid due date "+date)
+				content = append(content, w)
+				continue
+			}
+			t.due.Date = date
+		case strings.HasPrefix(w, "pri:") && len(w) == 5:
+			t.priority = t.parsePriority(w[4:])
+		default:
+			content = append(content, w)
+		}
+	}
+	t.content = strings.Join(content, " ")
+	return t
+}
+
+func (t *importTask) parsePriority(p string) int {
+	switch p {
+	case "A":
+		return 4
+	case "B":
+		return 3
+	case "C":
+		return 2
+	}
+	t.notes = append(t.notes, "priority "+p+" imported as p4")
+	return 1
+}
+
+// The name of the existing project with the todo.txt name, or else the name as it is
+func todoTxtProject(name string, projects []Project) string {
+	for _, p := range projects {
+		if todoTxtName(p.Name) == name {
+			return p.Name
+		}
+	}
+	return name
+}
+
+// The existing label with the todo.txt name, or else the name as it is
+func todoTxtLabel(name string, todos []Todo) string {
+	for _, t := range todos {
+		for _, l := range t.Labels {
+			if todoTxtName(l) == name {
+				return l
+			}
+		}
+		if l := todoTxtLabel(name, t.Children); l != name {
+			return l
+		}
+	}
+	return name
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportTodoTxt(t *testing.T) {
+	var b bytes.Buffer
+	err := exportTodoTxt(&b, exportData{
+		todos: []Todo{{
+			Content:     "Call mom",
+			ProjectName: "Family Stuff",
+			Labels:      []string{"phone"},
+			Priority:    4,
+			Due:         Due{Date: "2024-01-31T10:00:00"},
+			Children:    []Todo{{Content: "Find number", ProjectName: "Family Stuff", Priority: 1}},
+		}},
+		completed: []completedTask{{
+			Todo:        Todo{Content: "Buy milk", ProjectName: "Home", Checked: true},
+			CompletedAt: "2024-01-02T08:00:00Z",
+		}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, `(A) Call mom +Family_Stuff @phone due:2024-01-31
+Find number +Family_Stuff
+x 2024-01-02 Buy milk +Home
+`, b.String())
+}
+
+func TestParseTodoTxt(t *testing.T) {
+	data := exportData{
+		projects: []Project{{Id: "1", Name: "Family Stuff"}},
+		todos:    []Todo{{Id: "2", Children: []Todo{{Id: "3", Labels: []string{"deep work"}}}}},
+	}
+	tasks, err := parseTodoTxt(strings.NewReader(`(A) 2024-01-01 Call mom +Family_Stuff @phone @deep_work due:2024-01-31
+
+x 2024-01-03 2024-01-01 Buy milk +Home pri:B
+(D) Read http://example.com due:someday
+`), data)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(tasks))
+
+	require.Equal(t, "Call mom", tasks[0].content)
+	require.Equal(t, "Family Stuff", tasks[0].project)
+	require.Equal(t, []string{"phone", "deep work"}, tasks[0].labels)
+	require.Equal(t, "2024-01-31", tasks[0].due.Date)
+	require.Equal(t, 4, tasks[0].priority)
+	require.Equal(t, "line 1", tasks[0].ref)
+
+	require.True(t, tasks[1].checked)
+	require.Equal(t, "Buy milk", tasks[1].content)
+	require.Equal(t, "Home", tasks[1].project)
+	require.Equal(t, 3, tasks[1].priority)
+	require.Equal(t, "line 3", tasks[1].ref)
+
+	require.Equal(t, "Read http://example.com due:someday", tasks[2].content)
+	require.Equal(t, 1, tasks[2].priority)
+	require.Equal(t, 2, len(tasks[2].notes))
+}
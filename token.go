@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The API token is taken from, in order:
+// the TODOIST_API_TOKEN environment variable (for the default profile only), the output of token_command,
+// the token setting in the config file, or the token file.
+// When none is found, the TUI starts with a setup screen that saves the token to the token file.
+
+var errNoToken = errors.New("no todoist API token found")
+
+func resolveToken(c Profile) (string, error) {
+	if token := strings.TrimSpace(os.Getenv("TODOIST_API_TOKEN")); token != "" && c.tokenFromEnv {
+		return token, nil
+	}
+	if c.TokenCommand != "" {
+		out, err := exec.Command("sh", "-c", c.TokenCommand).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+				err = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return "", fmt.Errorf("token_command %q failed: %w", c.TokenCommand, err)
+		}
+		token := strings.TrimSpace(string(out))
+		if token == "" {
+			return "", fmt.Errorf("token_command %q did not print a token", c.TokenCommand)
+		}
+		return token, nil
+	}
+	if token := strings.TrimSpace(c.Token); token != "" {
+		return token, nil
+	}
+	t, err := os.ReadFile(c.TokenPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", noTokenError(c.TokenPath)
+	}
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(t))
+	if token == "" {
+		return "", noTokenError(c.TokenPath)
+	}
+	return token, nil
+}
+
+func noTokenError(path string) error {
+	return fmt.Errorf("%w: set TODOIST_API_TOKEN, token_command or token in the config file, or save the token in %s", errNoToken, path)
+}
+
+func saveToken(path, token string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(token+"\n"), 0600)
+}
+
+// Whether the TUI can be started, to show the setup screen
+func isTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+func (m *model) startSetup() {
+	m.setup = true
+	m.syncing = false
+	m.textInput.Focus()
+	m.textInput.SetValue("")
+	m.textInput.Prompt = ""
+	m.textInput.Placeholder = "paste your token"
+	m.textInput.EchoMode = textinput.EchoPassword
+}
+
+func (m model) updateSetup(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+		case "enter":
+			token := strings.TrimSpace(m.textInput.Value())
+			if token == "" {
+				return m, nil
+			}
+			err := saveToken(m.tokenPath, token)
+			if err != nil {
+				m.syncError = err
+				return m, nil
+			}
+			m.storage.api = NewAPI(token)
+			m.setup = false
+			m.syncError = nil
+			m.textInput.SetValue("")
+			m.textInput.Placeholder = ""
+			m.textInput.EchoMode = textinput.EchoNormal
+			m.textInput.Blur()
+			m.syncing = true
+			return m, m.getLocalTodos
+		}
+	}
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+func (m model) renderSetup() string {
+	width := m.totalWidth - 4
+	if width < 20 {
+		width = 20
+	}
+	text := lipgloss.NewStyle().Width(width)
+	s := "\n  " + chosenTextStyle.Render("Welcome to todui") + "\n\n"
+	s += lipgloss.NewStyle().PaddingLeft(2).Render(text.Render(
+		"No Todoist API token was found. You can find your token in Todoist under Settings → Integrations → Developer.")) + "\n\n"
+	s += "  API token: " + m.textInput.View() + "\n\n"
+	s += lipgloss.NewStyle().PaddingLeft(2).Render(dimTextStyle.Copy().Width(width).Render(
+		"The token is saved to "+m.tokenPath+". It can also be set with TODOIST_API_TOKEN, or token_command or token in the config file.")) + "\n"
+	if m.syncError != nil {
+		s += "\n  " + errorTextStyle.Render(m.syncError.Error()) + "\n"
+	}
+	s += "\n  " + helpTextStyle.Render("enter: save   esc: quit")
+	return s
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	t.Setenv("TODOIST_API_TOKEN", "")
+
+	_, err := resolveToken(Profile{TokenPath: path})
+	require.True(t, errors.Is(err, errNoToken))
+	require.Contains(t, err.Error(), path)
+
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0600))
+	token, err := resolveToken(Profile{TokenPath: path})
+	require.NoError(t, err)
+	require.Equal(t, "from-file", token)
+
+	token, err = resolveToken(Profile{TokenPath: path, Token: "from-config"})
+	require.NoError(t, err)
+	require.Equal(t, "from-config", token)
+
+	token, err = resolveToken(Profile{TokenPath: path, Token: "from-config", TokenCommand: "echo from-command"})
+	require.NoError(t, err)
+	require.Equal(t, "from-command", token)
+
+	t.Setenv("TODOIST_API_TOKEN", "from-env")
+	token, err = resolveToken(Profile{TokenPath: path, TokenCommand: "echo from-command", tokenFromEnv: true})
+	require.NoError(t, err)
+	require.Equal(t, "from-env", token)
+	// Other profiles use their own token
+	token, err = resolveToken(Profile{TokenPath: path, TokenCommand: "echo from-command"})
+	require.NoError(t, err)
+	require.Equal(t, "from-command", token)
+}
+
+func TestTokenCommandErrors(t *testing.T) {
+	t.Setenv("TODOIST_API_TOKEN", "")
+	_, err := resolveToken(Profile{TokenCommand: "echo locked >&2; exit 1"})
+	require.Error(t, err)
+	require.False(t, errors.Is(err, errNoToken))
+	require.Contains(t, err.Error(), "locked")
+
+	_, err = resolveToken(Profile{TokenCommand: "true"})
+	require.Error(t, err)
+}
+
+func TestSetupScreen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todui", "token")
+	m := NewModel(Storage{}, false)
+	m.tokenPath = path
+	m.startSetup()
+
+	var res tea.Model = m
+	res, _ = res.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	require.Contains(t, res.View(), "Welcome to todui")
+
+	// Nothing happens without a token
+	res, _ = res.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.True(t, res.(model).setup)
+
+	res, _ = res.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("secret")})
+	require.NotContains(t, res.View(), "secret")
+	res, cmd := res.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.NotNil(t, cmd)
+	m = res.(model)
+	require.False(t, m.setup)
+	require.Equal(t, "secret", m.storage.api.token)
+
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "secret", strings.TrimSpace(string(saved)))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
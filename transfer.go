@@ -0,0 +1,501 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Export and import of tasks in the formats of other tools:
+//
+//	todui export --format todotxt [--filter ...] [--completed] [file]
+//	todui import --format todotxt [--dry-run] file
+//
+// Exports read the local db, synced first unless --local is given.
+// Imports are sent as batched sync commands, creating the projects and sections that do not exist yet.
+// Imported tasks with the id of an existing task update it instead.
+
+type transferFormat struct {
+	name string
+	desc string
+	// File extension, for the export of the palette
+	ext string
+	// nil if the format can not be exported or imported
+	export func(w io.Writer, data exportData) error
+	parse  func(r io.Reader, data exportData) ([]importTask, error)
+}
+
+func transferFormats() []transferFormat {
+	return []transferFormat{
+		{name: "todotxt", desc: "todo.txt lines", ext: "txt", export: exportTodoTxt, parse: parseTodoTxt},
+		{name: "taskwarrior", desc: "json of task export and task import", ext: "json", export: exportTaskwarrior, parse: parseTaskwarrior},
+		{name: "markdown", desc: "projects as headings and tasks as checklists", ext: "md", export: exportMarkdown, parse: parseMarkdownProject},
+		{name: "csv", desc: "Todoist project templates", ext: "csv", export: exportCSV, parse: parseCSV},
+		{name: "org", desc: "Org-mode headlines, edits are imported as updates", ext: "org", export: exportOrg, parse: parseOrg},
+		{name: "ics", desc: "iCalendar tasks, and events with --events", ext: "ics", export: exportICS},
+	}
+}
+
+func transferFormatNames() []string {
+	names := make([]string, 0)
+	for _, f := range transferFormats() {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+func transferFormatHelp() string {
+	s := "Format, one of:"
+	for _, f := range transferFormats() {
+		s += "\n" + f.name + ": " + f.desc
+	}
+	return s
+}
+
+func findTransferFormat(name string) (transferFormat, error) {
+	for _, f := range transferFormats() {
+		if f.name == name {
+			return f, nil
+		}
+	}
+	if name == "" {
+		return transferFormat{}, usageError("missing --format, expected one of %s", strings.Join(transferFormatNames(), ", "))
+	}
+	return transferFormat{}, usageError("unknown format %q, expected one of %s", name, strings.Join(transferFormatNames(), ", "))
+}
+
+type completedTask struct {
+	Todo
+	CompletedAt string
+}
+
+type exportData struct {
+	todos     []Todo
+	completed []completedTask
+	projects  []Project
+	sections  []Section
+	// ics: also all-day events on the due dates
+	events bool
+}
+
+func (c cli) exportData(local bool) (exportData, error) {
+	var data exportData
+	todos, err := c.todos(local)
+	if err != nil {
+		return data, err
+	}
+	data.todos = todos
+	data.projects, err = c.storage.localProjects()
+	if err != nil {
+		return data, err
+	}
+	data.sections, err = c.storage.localSections()
+	if err != nil {
+		return data, err
+	}
+	completed, err := c.storage.localCompleted()
+	if err != nil {
+		return data, err
+	}
+	for _, item := range completed {
+		data.completed = append(data.completed, completedTask{
+			Todo: Todo{
+				Id:          item.Id,
+				ProjectId:   item.ProjectId,
+				ProjectName: data.projectName(item.ProjectId),
+				Content:     item.Content,
+				Checked:     true,
+			},
+			CompletedAt: item.CompletedAt,
+		})
+	}
+	return data, nil
+}
+
+func (d exportData) projectName(id string) string {
+	for _, p := range d.projects {
+		if p.Id == id {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+func (d exportData) sectionName(id string) string {
+	for _, s := range d.sections {
+		if s.Id == id {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+func (d *exportData) filter(filter string) {
+	d.todos = filterContents(d.todos, filter)
+	completed := make([]completedTask, 0, len(d.completed))
+	for _, t := range d.completed {
+		if len(filterContents([]Todo{t.Todo}, filter)) > 0 {
+			completed = append(completed, t)
+		}
+	}
+	d.completed = completed
+}
+
+func (c cli) exportFile(args []string) error {
+	fs := c.flagSet("export")
+	format := fs.String("format", "", transferFormatHelp())
+	filter := fs.String("filter", "", "Only export the tasks matching the filter.")
+	local := fs.Bool("local", false, "Use the local db without syncing first.")
+	completed := fs.Bool("completed", false, "Also export the completed tasks pulled by the last full sync.")
+	events := fs.Bool("events", false, "ics: also export all-day events on the due dates.")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("unexpected argument %q", fs.Arg(1))
+	}
+	f, err := findTransferFormat(*format)
+	if err != nil {
+		return err
+	}
+	if f.export == nil {
+		return usageError("%s can not be exported", f.name)
+	}
+	data, err := c.exportData(*local)
+	if err != nil {
+		return err
+	}
+	if !*completed {
+		data.completed = nil
+	}
+	data.events = *events
+	data.filter(*filter)
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		return f.export(c.stdout, data)
+	}
+	file, err := os.Create(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	err = f.export(file, data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (c cli) importFile(args []string) error {
+	fs := c.flagSet("import")
+	format := fs.String("format", "", transferFormatHelp())
+	dryRun := fs.Bool("dry-run", false, "Show what would be created, without changing anything.")
+	project := fs.String("project", "", "Project of the tasks the file does not give a project, instead of the inbox.")
+	local := fs.Bool("local", false, "Use the local db without syncing first.")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("expected one file, or - for stdin")
+	}
+	f, err := findTransferFormat(*format)
+	if err != nil {
+		return err
+	}
+	if f.parse == nil {
+		return usageError("%s can not be imported", f.name)
+	}
+	r := io.Reader(os.Stdin)
+	if fs.Arg(0) != "-" {
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	data, err := c.exportData(*local)
+	if err != nil {
+		return err
+	}
+	tasks, err := f.parse(r, data)
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		return errEmptyImport
+	}
+	for i := range tasks {
+		if tasks[i].project == "" {
+			tasks[i].project = *project
+		}
+	}
+	plan, err := planImport(tasks, data)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		plan.write(c.stdout, nil)
+		return nil
+	}
+	ids, err := c.storage.sendCommands(plan.commands)
+	plan.write(c.stdout, ids)
+	if err != nil {
+		return err
+	}
+	_, err = c.storage.fetchTodos()
+	return c.warnRejected(err)
+}
+
+// A task read from a file
+type importTask struct {
+	// Where the task came from, like a line number, shown in the report
+	ref string
+	// The task to update, empty to create a new task
+	id          string
+	content     string
+	description string
+	// Project name, empty for the inbox, or the project of the parent
+	project  string
+	section  string
+	priority int // As in the api, 4 is p1. 0 is not set.
+	labels   []string
+	// Date, or a String in natural language
+	due Due
+	// Org-mode repeater of the due date, like +1w
+	repeater string
+	checked  bool
+	children []importTask
+	// Things that could not be imported
+	notes []string
+}
+
+type importPlan struct {
+	commands []SyncCommand
+	lines    []planLine
+}
+
+type planLine struct {
+	indent int
+	action string
+	// The temp id of what is created, or the id of what is updated
+	key     string
+	ref     string
+	text    string
+	details string
+	notes   []string
+}
+
+// The sync commands that imports the tasks
+func planImport(tasks []importTask, data exportData) (importPlan, error) {
+	var p importPlan
+	projects := make(map[string]string)
+	for _, project := range data.projects {
+		projects[project.Name] = project.Id
+	}
+	sections := make(map[string]string)
+	for _, s := range data.sections {
+		sections[s.ProjectId+"/"+s.Name] = s.Id
+	}
+	existing := make(map[string]Todo)
+	var walk func(todos []Todo)
+	walk = func(todos []Todo) {
+		for _, t := range todos {
+			existing[t.Id] = t
+			walk(t.Children)
+		}
+	}
+	walk(data.todos)
+
+	projectId := func(name string) string {
+		if id, ok := projects[name]; ok {
+			return id
+		}
+		cmd := newTempCommand("project_add", map[string]interface{}{"name": name})
+		p.commands = append(p.commands, cmd)
+		p.lines = append(p.lines, planLine{action: "project", key: cmd.TempId, text: name})
+		projects[name] = cmd.TempId
+		return cmd.TempId
+	}
+	sectionId := func(project, name string) string {
+		if id, ok := sections[project+"/"+name]; ok {
+			return id
+		}
+		cmd := newTempCommand("section_add", map[string]interface{}{"name": name, "project_id": project})
+		p.commands = append(p.commands, cmd)
+		p.lines = append(p.lines, planLine{action: "section", key: cmd.TempId, text: name})
+		sections[project+"/"+name] = cmd.TempId
+		return cmd.TempId
+	}
+
+	var add func(t importTask, parent, parentProject string, indent int) error
+	add = func(t importTask, parent, parentProject string, indent int) error {
+		line := planLine{indent: indent, ref: t.ref, text: t.content, details: t.details(), notes: t.notes}
+		project := parentProject
+		if t.id != "" {
+			old, ok := existing[t.id]
+			if !ok {
+				return fmt.Errorf("%s: no task with id %s", t.ref, t.id)
+			}
+			args := map[string]interface{}{
+				"id":          t.id,
+				"content":     t.content,
+				"description": t.description,
+				"labels":      nonNil(t.labels),
+				"due":         dueArgs(t.due),
+			}
+			if t.priority != 0 {
+				args["priority"] = t.priority
+			}
+			p.commands = append(p.commands, newSyncCommand("item_update", args))
+			line.action = "update"
+			line.key = t.id
+			p.lines = append(p.lines, line)
+			project = old.ProjectId
+			if parent == "" && t.project != "" && t.project != old.ProjectName {
+				project = projectId(t.project)
+				p.commands = append(p.commands, newSyncCommand("item_move", map[string]interface{}{"id": t.id, "project_id": project}))
+			}
+			if t.checked && !old.Checked {
+				p.commands = append(p.commands, newSyncCommand("item_close", map[string]interface{}{"id": t.id}))
+			}
+		} else {
+			if t.content == "" {
+				return fmt.Errorf("%s: task without content", t.ref)
+			}
+			args := map[string]interface{}{"content": t.content}
+			if parent == "" && t.project != "" {
+				project = projectId(t.project)
+			}
+			if project != "" {
+				args["project_id"] = project
+			}
+			if parent != "" {
+				args["parent_id"] = parent
+			} else if t.section != "" && project != "" {
+				args["section_id"] = sectionId(project, t.section)
+			} else if t.section != "" {
+				line.notes = append(line.notes, "section "+t.section+" needs a project")
+			}
+			if t.description != "" {
+				args["description"] = t.description
+			}
+			if t.priority != 0 {
+				args["priority"] = t.priority
+			}
+			if len(t.labels) > 0 {
+				args["labels"] = t.labels
+			}
+			if due := dueArgs(t.due); due != nil {
+				args["due"] = due
+			}
+			cmd := newTempCommand("item_add", args)
+			p.commands = append(p.commands, cmd)
+			line.action = "task"
+			line.key = cmd.TempId
+			p.lines = append(p.lines, line)
+			if t.checked {
+				p.commands = append(p.commands, newSyncCommand("item_close", map[string]interface{}{"id": cmd.TempId}))
+			}
+		}
+		for _, child := range t.children {
+			err := add(child, line.key, project, indent+1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, t := range tasks {
+		err := add(t, "", "", 0)
+		if err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+func newTempCommand(t string, args map[string]interface{}) SyncCommand {
+	cmd := newSyncCommand(t, args)
+	cmd.TempId = uuid.New().String()
+	return cmd
+}
+
+// The due of item_add and item_update. A recurring date is sent as its string, to keep recurring.
+func dueArgs(due Due) map[string]interface{} {
+	if due.String != "" && (due.IsRecurring || due.Date == "") {
+		return map[string]interface{}{"string": due.String}
+	}
+	if due.Date != "" {
+		return map[string]interface{}{"date": due.Date}
+	}
+	return nil
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func (t importTask) details() string {
+	s := make([]string, 0)
+	if t.project != "" {
+		s = append(s, "#"+t.project)
+	}
+	if t.section != "" {
+		s = append(s, "/"+t.section)
+	}
+	if t.due.Date != "" {
+		s = append(s, "due "+t.due.Date)
+	} else if t.due.String != "" {
+		s = append(s, "due "+t.due.String)
+	}
+	if t.priority > 1 {
+		s = append(s, renderPriority(t.priority))
+	}
+	if len(t.labels) > 0 {
+		s = append(s, cliLabels(t.labels))
+	}
+	if t.checked {
+		s = append(s, "done")
+	}
+	return strings.Join(s, " ")
+}
+
+// Writes what will be done, or with the ids, what was done
+func (p importPlan) write(w io.Writer, ids map[string]string) {
+	for _, l := range p.lines {
+		indent := strings.Repeat("  ", l.indent)
+		verb := "create " + l.action
+		if l.action == "update" {
+			verb = "update task " + l.key
+		}
+		if ids != nil {
+			id, ok := ids[l.key]
+			if !ok && l.action != "update" {
+				continue // Not sent
+			}
+			verb = "created " + l.action + " " + id
+			if l.action == "update" {
+				verb = "updated task " + l.key
+			}
+		}
+		s := fmt.Sprintf("%s%s %q", indent, verb, l.text)
+		if l.details != "" {
+			s += " " + l.details
+		}
+		if l.ref != "" {
+			s += " (" + l.ref + ")"
+		}
+		fmt.Fprintln(w, s)
+		for _, note := range l.notes {
+			fmt.Fprintf(w, "%s  note: %s\n", indent, note)
+		}
+	}
+}
+
+var errEmptyImport = errors.New("nothing to import")
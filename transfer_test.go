@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanImport(t *testing.T) {
+	data := exportData{
+		projects: []Project{{Id: "11", Name: "Home"}},
+		sections: []Section{{Id: "21", ProjectId: "11", Name: "Kitchen"}},
+		todos:    []Todo{{Id: "1", Content: "old", ProjectId: "11", ProjectName: "Home"}},
+	}
+	plan, err := planImport([]importTask{
+		{content: "Buy milk", project: "Home", section: "Kitchen", priority: 4, checked: true},
+		{content: "Write report", project: "Work", section: "Q1", due: Due{Date: "2024-01-01"}, children: []importTask{
+			{content: "Draft"},
+		}},
+		{id: "1", content: "new", project: "Home"},
+	}, data)
+	require.NoError(t, err)
+
+	types := make([]string, len(plan.commands))
+	for i, cmd := range plan.commands {
+		types[i] = cmd.Type
+	}
+	require.Equal(t, []string{"item_add", "item_close", "project_add", "section_add", "item_add", "item_add", "item_update"}, types)
+
+	milk := plan.commands[0]
+	require.Equal(t, "11", milk.Args["project_id"])
+	require.Equal(t, "21", milk.Args["section_id"])
+	require.Equal(t, milk.TempId, plan.commands[1].Args["id"])
+
+	project := plan.commands[2].TempId
+	require.Equal(t, project, plan.commands[3].Args["project_id"])
+	report := plan.commands[4]
+	require.Equal(t, project, report.Args["project_id"])
+	require.Equal(t, plan.commands[3].TempId, report.Args["section_id"])
+	require.Equal(t, map[string]interface{}{"date": "2024-01-01"}, report.Args["due"])
+	draft := plan.commands[5]
+	require.Equal(t, report.TempId, draft.Args["parent_id"])
+	require.Equal(t, project, draft.Args["project_id"])
+
+	require.Equal(t, "new", plan.commands[6].Args["content"])
+	require.Nil(t, plan.commands[6].Args["due"])
+
+	var b bytes.Buffer
+	plan.write(&b, nil)
+	require.Equal(t, `create task "Buy milk" #Home /Kitchen p1 done
+create project "Work"
+create section "Q1"
+create task "Write report" #Work /Q1 due 2024-01-01
+  create task "Draft"
+update task 1 "new" #Home
+`, b.String())
+
+	_, err = planImport([]importTask{{id: "404", content: "x", ref: "line 1"}}, data)
+	require.EqualError(t, err, "line 1: no task with id 404")
+}
@@ -1,12 +1,9 @@
 package main
 
-import (
-	"strings"
-	"time"
-)
-
 type FetchedTodos struct {
 	data []Todo
+	// Queued commands the server rejected, the sync went on without them
+	rejected error
 }
 
 type LocalTodos struct {
@@ -52,6 +49,22 @@ type SyncError struct {
 	err error
 }
 
+type Section struct {
+	Id           string `json:"id"`
+	ProjectId    string `json:"project_id"`
+	Name         string `json:"name"`
+	SectionOrder int    `json:"section_order"`
+	IsDeleted    bool   `json:"is_deleted"`
+	IsArchived   bool   `json:"is_archived"`
+}
+
+type Label struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	IsDeleted bool   `json:"is_deleted"`
+}
+
 type Project struct {
 	Id           string `json:"id"`
 	Name         string `json:"name"`
@@ -67,65 +80,63 @@ type Project struct {
 
 // Todo might need to be an interface.. because CompletedItem looks very different..
 type Todo struct {
-	Id          string
-	ProjectId   string
-	ProjectName string
-	Content     string
-	Description string
-	Priority    int
-	Labels      []string
-	Checked     bool
-	Children    []Todo
-	Due         Due
+	Id           string
+	ProjectId    string
+	ProjectName  string
+	ProjectColor string
+	SectionId    string
+	Content      string
+	Description  string
+	Priority     int
+	Labels       []string
+	Checked      bool
+	Children     []Todo
+	Due          Due
 }
 
 func (t Todo) DueTodayOrBefore() bool {
-	due, err := time.Parse("2006-01-02", t.Due.Date)
+	due, _, err := t.Due.Time()
+	if err != nil {
+		return false
+	}
+	return daysBetween(dateFormatter.now(), due) <= 0
+}
+
+// Full-day tasks are overdue the day after, tasks with a time as soon as the time has passed.
+func (t Todo) Overdue() bool {
+	due, hasTime, err := t.Due.Time()
 	if err != nil {
 		return false
 	}
-	return !due.After(time.Now())
+	now := dateFormatter.now()
+	if hasTime {
+		return due.Before(now)
+	}
+	return daysBetween(now, due) < 0
 }
 
 func (t Todo) DueDisplay(withExtraInfo bool) string {
-	width := 10
+	width := 15
 	if withExtraInfo {
-		width = 30
+		width = 35
 	}
 	if t.Due.Date == "" {
 		return dueDateStyle.Width(width).Render("")
 	}
-	result := ""
-	overdue := false
-	dateTimeOnly := strings.Split(t.Due.Date, "T")[0]
-	parsed, err := time.Parse("2006-01-02", dateTimeOnly)
+	parsed, hasTime, err := t.Due.Time()
 	if err != nil {
-		return dueDateStyle.Width(10).Render(t.Due.Date)
-	}
-	if parsed.Before(time.Now().AddDate(0, 0, 7)) {
-		today := time.Now().Day()
-		switch parsed.Day() {
-		case today:
-			result = "Today"
-		case today + 1:
-			result = "Tomorrow"
-		case today - 1:
-			result = "Yesterday"
-			overdue = true
-		default:
-			result = parsed.Weekday().String()
-			overdue = parsed.Before(time.Now())
-		}
-	} else {
-		result = parsed.Format("02/01/2006")
-		overdue = parsed.Before(time.Now())
+		return dueDateStyle.Width(width).Render(t.Due.Date)
 	}
+	result := dateFormatter.format(parsed, hasTime)
 
 	if withExtraInfo {
 		result += ", " + t.Due.String
+		if t.Due.Timezone != "" {
+			result += " (" + t.Due.Timezone + ")"
+		}
 	}
 
-	if overdue {
+	if t.Overdue() {
 		return dueDateOverdueStyle.Width(width).Render(result)
 	} else {
 		return dueDateStyle.Width(width).Render(result)
@@ -137,7 +148,7 @@ func (t Todo) ProjectDisplay(projectNameLength int) string {
 	if t.ProjectName != "" {
 		project = "#" + t.ProjectName
 	}
-	return projectStyle.Width(projectNameLength + 1).Render(project)
+	return projectColorStyle(t.ProjectColor).Width(projectNameLength + 1).Render(project)
 }
 
 type Due struct {
@@ -152,6 +163,7 @@ type Due struct {
 type Item struct {
 	Id          string   `json:"id"`
 	ProjectId   string   `json:"project_id"`
+	SectionId   string   `json:"section_id"`
 	Content     string   `json:"content"`
 	Description string   `json:"description"`
 	Priority    int      `json:"priority"`
@@ -162,9 +174,10 @@ type Item struct {
 }
 
 type CompletedItem struct {
-	Id            string `json:"id"`
-	ProjectId     string `json:"project_id"`
-	Content       string `json:"content"`
-	MetaData      string `json:"meta_data"`
-	CompletedDate string `json:"completed_date"`
+	Id          string `json:"id"`
+	ProjectId   string `json:"project_id"`
+	Content     string `json:"content"`
+	MetaData    string `json:"meta_data"`
+	TaskId      string `json:"task_id"`
+	CompletedAt string `json:"completed_at"`
 }
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Upcoming tab: an agenda of the next n days, grouped by day, with overdue tasks on top.
+
+type agendaDay struct {
+	date    time.Time
+	overdue bool
+	todos   []Todo
+}
+
+func (d agendaDay) title(now time.Time) string {
+	if d.overdue {
+		return "Overdue"
+	}
+	rel := dateFormatter.format(d.date, false)
+	abs := d.date.Format("Mon 02 Jan")
+	if rel == d.date.Format(dateFormatter.absoluteFormat) {
+		return abs
+	}
+	return rel + " · " + abs
+}
+
+// Tasks due before today or within the next `days` days, sorted in agenda order.
+func filterUpcoming(list []Todo, now time.Time, days int) []Todo {
+	var newList = make([]Todo, 0)
+	for _, t := range list {
+		due, _, err := t.Due.Time()
+		if err != nil {
+			continue
+		}
+		if daysBetween(now, due) < days {
+			newList = append(newList, t)
+		}
+	}
+	sort.Stable(ByDueThenPriority(newList))
+	return newList
+}
+
+// Groups the tasks (as returned by filterUpcoming) by day.
+// The overdue bucket and empty days are only included when they are not empty, or showEmpty is set.
+func buildAgenda(list []Todo, now time.Time, days int, showEmpty bool) []agendaDay {
+	overdue := agendaDay{overdue: true, todos: make([]Todo, 0)}
+	agenda := make([]agendaDay, days)
+	for i := range agenda {
+		agenda[i] = agendaDay{
+			date:  time.Date(now.Year(), now.Month(), now.Day()+i, 0, 0, 0, 0, now.Location()),
+			todos: make([]Todo, 0),
+		}
+	}
+	for _, t := range list {
+		due, _, err := t.Due.Time()
+		if err != nil {
+			continue
+		}
+		d := daysBetween(now, due)
+		switch {
+		case d < 0:
+			overdue.todos = append(overdue.todos, t)
+		case d < days:
+			agenda[d].todos = append(agenda[d].todos, t)
+		}
+	}
+	result := make([]agendaDay, 0, days+1)
+	if len(overdue.todos) > 0 {
+		result = append(result, overdue)
+	}
+	for _, day := range agenda {
+		if showEmpty || len(day.todos) > 0 {
+			result = append(result, day)
+		}
+	}
+	return result
+}
+
+func (m model) agenda() []agendaDay {
+	return buildAgenda(m.upcomingTodos, dateFormatter.now(), m.upcomingDays, m.showEmptyDays)
+}
+
+// Index of the first task of each day in the flat upcoming list
+func agendaOffsets(agenda []agendaDay) []int {
+	offsets := make([]int, len(agenda))
+	total := 0
+	for i, day := range agenda {
+		offsets[i] = total
+		total += len(day.todos)
+	}
+	return offsets
+}
+
+// Moves the focused day to the day of the task under the cursor
+func (m *model) focusCursorDay() {
+	agenda := m.agenda()
+	offsets := agendaOffsets(agenda)
+	for i := len(agenda) - 1; i >= 0; i-- {
+		if len(agenda[i].todos) > 0 && m.cursor.index >= offsets[i] {
+			m.focusedDay = i
+			return
+		}
+	}
+	m.focusedDay = 0
+}
+
+// Moves the focused day by delta. When moveCursor is set and the day has tasks,
+// the cursor jumps to the first task of the day.
+func (m *model) moveFocusedDay(delta int, moveCursor bool) {
+	agenda := m.agenda()
+	if len(agenda) == 0 {
+		return
+	}
+	m.focusedDay += delta
+	if m.focusedDay < 0 {
+		m.focusedDay = 0
+	}
+	if m.focusedDay >= len(agenda) {
+		m.focusedDay = len(agenda) - 1
+	}
+	if moveCursor && len(agenda[m.focusedDay].todos) > 0 {
+		m.cursor.index = agendaOffsets(agenda)[m.focusedDay]
+	}
+}
+
+// Moves the task to the given day, keeping the time of day if it has one
+func rescheduleTodo(todo Todo, day time.Time) Todo {
+	date := day.Format(dueDateLayout)
+	if due, hasTime, err := todo.Due.Time(); err == nil && hasTime {
+		t := time.Date(day.Year(), day.Month(), day.Day(), due.Hour(), due.Minute(), due.Second(), 0, time.Local)
+		date = formatDueDate(t, todo.Due.Date, true)
+	}
+	if todo.Due.IsRecurring {
+		// Setting a date directly would remove the recurrence
+		todo.Due.ChangeString = fmt.Sprintf("%s starting %s", todo.Due.String, day.Format(dueDateLayout))
+	} else {
+		todo.Due.ChangeString = ""
+	}
+	todo.Due.Date = date
+	return todo
+}
+
+func (m model) rescheduleToFocusedDay() (model, tea.Cmd) {
+	agenda := m.agenda()
+	if m.focusedDay >= len(agenda) || agenda[m.focusedDay].overdue {
+		return m, nil
+	}
+	todo, err := m.getCurrentTodo()
+	if err != nil {
+		return m, nil
+	}
+	m.syncing = true
+	return m, m.editTask(EditTaskData{
+		todo: rescheduleTodo(todo, agenda[m.focusedDay].date),
+	})
+}
+
+func (m model) renderAgenda(todos []Todo) string {
+	agenda := m.agenda()
+	projectLength := projectNameSize(todos, 30)
+	width := m.totalWidth
+	if m.showInfo {
+		width = (m.totalWidth / 2)
+	}
+	now := dateFormatter.now()
+
+	// A row is either the header of a day, or a task
+	type row struct {
+		day   int
+		index int // -1 for headers
+	}
+	rows := make([]row, 0, len(todos)+len(agenda))
+	index := 0
+	for i, day := range agenda {
+		rows = append(rows, row{day: i, index: -1})
+		for range day.todos {
+			rows = append(rows, row{day: i, index: index})
+			index++
+		}
+	}
+
+	content := ""
+	info := ""
+	start, end := m.offset, m.offset+m.viewHeight()
+	if end > len(rows) {
+		end = len(rows)
+	}
+	if start > end {
+		start = end
+	}
+	for _, r := range rows[start:end] {
+		if r.index == -1 {
+			day := agenda[r.day]
+			title := day.title(now)
+			if r.day == m.focusedDay {
+				title = chosenTextStyle.Render("▸ " + title)
+			} else if day.overdue {
+				title = dueDateOverdueStyle.Render("  " + title)
+			} else {
+				title = dimTextStyle.Render("  " + title)
+			}
+			content += title + "\n"
+			continue
+		}
+		v := todos[r.index]
+		if m.cursor.index == r.index {
+			content += "→ " + v.renderInList(width, projectLength)
+			if m.showInfo {
+				info = m.renderInfo(v, m.totalHeight) + "\n"
+			}
+		} else {
+			content += "  " + v.renderInList(width, projectLength)
+		}
+		content += "\n"
+	}
+	content += "\n" + m.scrollIndicator(len(rows))
+	if m.showInfo {
+		content = lipgloss.NewStyle().
+			Width(m.totalWidth / 2).
+			Render(content)
+
+		info = lipgloss.NewStyle().
+			Width(m.totalWidth / 2).
+			Render(info)
+
+		return lipgloss.JoinHorizontal(lipgloss.Top, content, info)
+	}
+	return content
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildAgenda(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.Local)
+	todos := []Todo{
+		{Id: "later", Due: Due{Date: "2024-02-20"}},
+		{Id: "tomorrow", Due: Due{Date: "2024-02-01"}},
+		{Id: "overdue", Due: Due{Date: "2024-01-02"}},
+		{Id: "today", Due: Due{Date: "2024-01-31T09:00:00"}},
+		{Id: "no due"},
+	}
+	upcoming := filterUpcoming(todos, now, 7)
+	require.Equal(t, 3, len(upcoming))
+	require.Equal(t, "overdue", upcoming[0].Id)
+
+	agenda := buildAgenda(upcoming, now, 7, false)
+	require.Equal(t, 3, len(agenda))
+	require.True(t, agenda[0].overdue)
+	require.Equal(t, "today", agenda[1].todos[0].Id)
+	require.Equal(t, "tomorrow", agenda[2].todos[0].Id)
+	require.Equal(t, time.February, agenda[2].date.Month())
+	require.Equal(t, []int{0, 1, 2}, agendaOffsets(agenda))
+
+	agenda = buildAgenda(upcoming, now, 7, true)
+	require.Equal(t, 8, len(agenda))
+	require.Equal(t, 0, len(agenda[3].todos))
+}
+
+func TestRescheduleTodo(t *testing.T) {
+	day := time.Date(2024, 2, 3, 0, 0, 0, 0, time.Local)
+
+	todo := rescheduleTodo(Todo{Due: Due{Date: "2024-01-31"}}, day)
+	require.Equal(t, "2024-02-03", todo.Due.Date)
+
+	todo = rescheduleTodo(Todo{}, day)
+	require.Equal(t, "2024-02-03", todo.Due.Date)
+
+	todo = rescheduleTodo(Todo{Due: Due{Date: "2024-01-31T14:30:00"}}, day)
+	require.Equal(t, "2024-02-03T14:30:00", todo.Due.Date)
+
+	todo = rescheduleTodo(Todo{Due: Due{Date: "2024-01-31", String: "every week", IsRecurring: true}}, day)
+	require.Equal(t, "every week starting 2024-02-03", todo.Due.ChangeString)
+}
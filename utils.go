@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -19,6 +20,10 @@ func creatFileIfNotExist(path string) error {
 	_, err := os.Stat(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
+			err = os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				return err
+			}
 			return os.WriteFile(path, []byte(""), 0644)
 		}
 	}
@@ -126,27 +131,30 @@ func withSize(s string, i int) string {
 	return total + "..."
 }
 
-func getProjectName(projects []Project, id string) string {
+func getProject(projects []Project, id string) Project {
 	for _, p := range projects {
 		if p.Id == id {
-			return p.Name
+			return p
 		}
 	}
-	return ""
+	return Project{}
 }
 
 func toTodo(item Item, projects []Project) Todo {
+	project := getProject(projects, item.ProjectId)
 	return Todo{
-		Id:          item.Id,
-		ProjectName: getProjectName(projects, item.ProjectId),
-		ProjectId:   item.ProjectId,
-		Content:     item.Content,
-		Description: item.Description,
-		Priority:    item.Priority,
-		Labels:      item.Labels,
-		Checked:     item.Checked,
-		Due:         item.Due,
-		Children:    []Todo{},
+		Id:           item.Id,
+		ProjectName:  project.Name,
+		ProjectColor: project.Color,
+		ProjectId:    item.ProjectId,
+		SectionId:    item.SectionId,
+		Content:      item.Content,
+		Description:  item.Description,
+		Priority:     item.Priority,
+		Labels:       item.Labels,
+		Checked:      item.Checked,
+		Due:          item.Due,
+		Children:     []Todo{},
 	}
 }
 
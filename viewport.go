@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+)
+
+// The list only renders the rows that are visible. The offset is the first visible row,
+// and is moved along with the cursor so the cursor is always visible.
+
+// Returns the new offset, so the cursor row is visible in a view of `height` rows
+func scrollOffset(offset, cursor, height, total int) int {
+	if height < 1 {
+		height = 1
+	}
+	if cursor < offset {
+		offset = cursor
+	}
+	if cursor >= offset+height {
+		offset = cursor - height + 1
+	}
+	if offset > total-height {
+		offset = total - height
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return offset
+}
+
+// Number of rows available for the list
+func (m model) viewHeight() int {
+	h := m.listHeight
+	if m.tab == calendarTab {
+		h -= calendarHeight(m.calendar.day)
+	}
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+// The row of the cursor, and the total number of rows in the list.
+// Only differs from the cursor index in the upcoming tab, where each day has a header row.
+func (m model) cursorRow() (int, int) {
+	if m.tab != upcomingTab {
+		return m.cursor.index, len(m.getMainList())
+	}
+	row, total := m.cursor.index, 0
+	offsets := agendaOffsets(m.agenda())
+	for i, day := range m.agenda() {
+		total += len(day.todos) + 1
+		if m.cursor.index >= offsets[i] {
+			row++
+		}
+	}
+	return row, total
+}
+
+func (m *model) keepCursorVisible() {
+	row, total := m.cursorRow()
+	m.offset = scrollOffset(m.offset, row, m.viewHeight(), total)
+}
+
+// Moves the cursor and the view by delta rows
+func (m *model) scrollBy(delta int) {
+	maxIndex := len(m.getMainList()) - 1
+	if maxIndex < 0 {
+		return
+	}
+	m.cursor.index += delta
+	if m.cursor.index < 0 {
+		m.cursor.index = 0
+	}
+	if m.cursor.index > maxIndex {
+		m.cursor.index = maxIndex
+	}
+	m.offset += delta
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
+
+func (m model) scrollIndicator(total int) string {
+	if total == 0 {
+		return "showing 0 of 0"
+	}
+	last := m.offset + m.viewHeight()
+	if last > total {
+		last = total
+	}
+	s := fmt.Sprintf("showing %d-%d of %d", m.offset+1, last, total)
+	if total > m.viewHeight() {
+		s += fmt.Sprintf(" (%d%%)", last*100/total)
+	}
+	if m.offset > 0 {
+		s += " ↑"
+	}
+	if last < total {
+		s += " ↓"
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScrollOffset(t *testing.T) {
+	require.Equal(t, 0, scrollOffset(0, 5, 10, 100))
+	require.Equal(t, 1, scrollOffset(0, 10, 10, 100))
+	require.Equal(t, 5, scrollOffset(10, 5, 10, 100))
+	require.Equal(t, 90, scrollOffset(95, 99, 10, 100))
+	// List is shorter than the view
+	require.Equal(t, 0, scrollOffset(3, 4, 10, 5))
+	require.Equal(t, 0, scrollOffset(0, 0, 10, 0))
+}
+
+func newScrollModel(n int) model {
+	todos := make([]Todo, n)
+	for i := range todos {
+		todos[i] = Todo{
+			Id:      fmt.Sprint(i),
+			Content: fmt.Sprintf("task number %d", i),
+			Due:     Due{Date: time.Now().Format(dueDateLayout)},
+		}
+	}
+	m := NewModel(Storage{}, false)
+	m.tab = allTasksTab
+	m.showInfo = false
+	var res tea.Model = m
+	res, _ = res.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	res, _ = res.Update(FetchedTodos{data: todos})
+	return res.(model)
+}
+
+func press(m model, keys ...string) model {
+	var res tea.Model = m
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "pgdown":
+			msg = tea.KeyMsg{Type: tea.KeyPgDown}
+		case "pgup":
+			msg = tea.KeyMsg{Type: tea.KeyPgUp}
+		case "ctrl+d":
+			msg = tea.KeyMsg{Type: tea.KeyCtrlD}
+		case "ctrl+u":
+			msg = tea.KeyMsg{Type: tea.KeyCtrlU}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		res, _ = res.Update(msg)
+	}
+	return res.(model)
+}
+
+// The cursor should always be visible, in lists of any size
+func TestViewportKeepsCursorVisible(t *testing.T) {
+	m := newScrollModel(5000)
+	height := m.viewHeight()
+
+	for i := 0; i < height+5; i++ {
+		m = press(m, "j")
+	}
+	require.Equal(t, height+5, m.cursor.index)
+	require.Equal(t, 6, m.offset)
+	require.Contains(t, m.View(), "→ ")
+	require.Contains(t, m.View(), fmt.Sprintf("task number %d ", height+5))
+
+	m = press(m, "pgdown")
+	require.Equal(t, 2*height+5, m.cursor.index)
+	require.Equal(t, height+6, m.offset)
+
+	m = press(m, "ctrl+u")
+	require.Equal(t, 2*height+5-height/2, m.cursor.index)
+
+	m = press(m, "G")
+	require.Equal(t, 4999, m.cursor.index)
+	require.Equal(t, 5000-height, m.offset)
+	require.Contains(t, m.View(), fmt.Sprintf("showing %d-5000 of 5000", 5001-height))
+
+	m = press(m, "g")
+	require.Equal(t, 0, m.offset)
+	require.Contains(t, m.View(), "task number 0 ")
+
+	// Only the visible rows are rendered
+	require.Less(t, strings.Count(m.View(), "task number"), height+1)
+}
+
+func TestViewportUpcoming(t *testing.T) {
+	m := newScrollModel(3000)
+	m = press(m, "5")
+	require.Equal(t, upcomingTab, m.tab)
+	m = press(m, "G")
+	row, total := m.cursorRow()
+	require.Equal(t, 3000, row) // one header row
+	require.Equal(t, 3001, total)
+	require.Contains(t, m.View(), "task number 2999 ")
+}